@@ -0,0 +1,20 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"net/http"
+)
+
+// requireAdmin checks that username is an existing user listed in the admin
+// configuration, answering with 401 or 403 itself otherwise.
+func (app *application) requireAdmin(w http.ResponseWriter, r *http.Request, username string) bool {
+	if _, ok := app.requestUser(w, r, username); !ok {
+		return false
+	}
+
+	if !containsString(app.config.Admin.Usernames, username) {
+		app.forbiddenResponse(w, r, data.ErrNoRights)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"avitotask/internal/config"
+	"avitotask/internal/data"
+	"avitotask/internal/i18n"
+	"avitotask/internal/seed"
+	"avitotask/internal/validator"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// errAdminUsage marks a mistake in the command line rather than a failure
+// of the operation; it has already been reported.
+var errAdminUsage = errors.New("invalid usage")
+
+// adminTool runs the "api admin" subcommands against the database through
+// the same models as the API.
+type adminTool struct {
+	config config.Config
+	models data.Models
+	json   bool
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+}
+
+type adminSubcommand struct {
+	name        string
+	description string
+	run         func(a *adminTool, args []string) error
+}
+
+var adminSubcommands = []adminSubcommand{
+	{"tenders", "list tenders of every organization and visibility", (*adminTool).listTenders},
+	{"bids", "list bids of every author and status", (*adminTool).listBids},
+	{"tender-status", "force the status of a tender, with an audit note", (*adminTool).tenderStatus},
+	{"bid-status", "force the status of a bid, with an audit note", (*adminTool).bidStatus},
+	{"recompute-approvals", "count approvals and close tenders that reached the quorum", (*adminTool).recomputeApprovals},
+	{"purge-history", "delete old versions of tenders and bids", (*adminTool).purgeHistory},
+	{"user", "show a user and the organizations they are responsible for", (*adminTool).user},
+	{"audit", "list the audit notes of forced changes", (*adminTool).audit},
+	{"seed", "create deterministic demo organizations, users, tenders and bids", (*adminTool).seed},
+	{"migrate", "apply the database migrations the server applies on start", (*adminTool).migrate},
+}
+
+// adminCommand runs "api admin [flags] <subcommand> [flags]". Read-only
+// subcommands print a table, or JSON with -json. The exit status is 2 for
+// a wrong command line and 1 if the operation failed.
+func adminCommand(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configFile := fs.String("config", "", "path to a JSON or YAML config file")
+	dsn := fs.String("db-dsn", "", "PostgreSQL DSN")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: api admin [flags] <subcommand> [flags]")
+		fmt.Fprintln(stderr, "\nsubcommands:")
+		tw := tabwriter.NewWriter(stderr, 0, 4, 2, ' ', 0)
+		for _, sub := range adminSubcommands {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.name, sub.description)
+		}
+		tw.Flush()
+		fmt.Fprintln(stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	i := slices.IndexFunc(adminSubcommands, func(sub adminSubcommand) bool { return sub.name == fs.Arg(0) })
+	if i < 0 {
+		fmt.Fprintf(stderr, "unknown subcommand %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = append(configArgs, "-config", *configFile)
+	}
+	if *dsn != "" {
+		configArgs = append(configArgs, "-db-dsn", *dsn)
+	}
+	cfg, _, err := config.Load(configArgs, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	a := &adminTool{
+		config: cfg,
+		models: data.NewModels(db),
+		json:   *asJSON,
+		stdout: stdout,
+		stderr: stderr,
+		getenv: getenv,
+	}
+	// Only migrate and seed change the schema. The other subcommands refuse
+	// to work on a database the server has not migrated to this build.
+	if name := adminSubcommands[i].name; name != "migrate" && name != "seed" {
+		if err := a.checkSchema(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	if err := adminSubcommands[i].run(a, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errAdminUsage) {
+			return 2
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func (a *adminTool) checkSchema() error {
+	return checkSchema(a.models)
+}
+
+// checkSchema reports an error when the database has not been migrated to
+// the schema this build expects. Command line tools call it instead of
+// migrating themselves.
+func checkSchema(models data.Models) error {
+	version, err := models.Tables.CurrentSchemaVersion(context.Background())
+	if err != nil {
+		return fmt.Errorf("reading the schema version: %w", err)
+	}
+	if version != data.SchemaVersion() {
+		return fmt.Errorf("database schema is at version %d, this build expects %d: start the server or run \"api admin migrate\"", version, data.SchemaVersion())
+	}
+	return nil
+}
+
+// parse parses the flags of a subcommand and reports validation errors
+// found by check.
+func (a *adminTool) parse(fs *flag.FlagSet, args []string, check func(v *validator.Validator)) error {
+	fs.SetOutput(a.stderr)
+	if err := fs.Parse(args); err != nil {
+		return errAdminUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(a.stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return errAdminUsage
+	}
+
+	v := validator.New()
+	check(v)
+	if v.Valid() {
+		return nil
+	}
+	errs := v.Localized(i18n.Default)
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	for _, field := range fields {
+		fmt.Fprintf(a.stderr, "-%s: %s\n", field, errs[field])
+	}
+	return errAdminUsage
+}
+
+// print writes v as JSON with -json, and the table of header and rows
+// otherwise.
+func (a *adminTool) print(v any, header []string, rows [][]string) error {
+	if a.json {
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// actor is who forced a change, for the audit note: the -actor flag, or
+// else the operating system user.
+func (a *adminTool) actor(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if user := a.getenv("USER"); user != "" {
+		return user
+	}
+	return "admin"
+}
+
+func (a *adminTool) listTenders(args []string) error {
+	fs := flag.NewFlagSet("tenders", flag.ContinueOnError)
+	var f data.TenderFilter
+	fs.StringVar(&f.OrganizationId, "org", "", "only tenders of this organization")
+	fs.StringVar(&f.Status, "status", "", "only tenders in this status")
+	limit := fs.Int("limit", 50, "maximum number of tenders, 0 for all")
+	offset := fs.Int("offset", 0, "number of tenders to skip")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("org", f.OrganizationId, validator.UUID())
+		v.Field("status", f.Status, validator.OneOf(data.TenderStatuses...))
+		v.CheckMessage(*limit >= 0, "limit", "validation.min", 0)
+		v.CheckMessage(*offset >= 0, "offset", "validation.min", 0)
+	})
+	if err != nil {
+		return err
+	}
+	f.Limit, f.Offset = int32(*limit), int32(*offset)
+
+	tenders, err := a.models.Tenders.ListTenders(f)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(tenders))
+	for _, t := range tenders {
+		rows = append(rows, []string{t.Id, t.Name, t.Status, t.Visibility, strconv.Itoa(t.Version), t.OrganizationId, t.CreatedAt})
+	}
+	return a.print(tenders, []string{"ID", "NAME", "STATUS", "VISIBILITY", "VERSION", "ORGANIZATION", "CREATED"}, rows)
+}
+
+func (a *adminTool) listBids(args []string) error {
+	fs := flag.NewFlagSet("bids", flag.ContinueOnError)
+	var f data.BidFilter
+	fs.StringVar(&f.TenderId, "tender", "", "only bids on this tender")
+	fs.StringVar(&f.AuthorId, "author", "", "only bids by this user or organization")
+	fs.StringVar(&f.Status, "status", "", "only bids in this status")
+	limit := fs.Int("limit", 50, "maximum number of bids, 0 for all")
+	offset := fs.Int("offset", 0, "number of bids to skip")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("tender", f.TenderId, validator.UUID())
+		v.Field("author", f.AuthorId, validator.UUID())
+		v.Field("status", f.Status, validator.OneOf(data.BidStatuses...))
+		v.CheckMessage(*limit >= 0, "limit", "validation.min", 0)
+		v.CheckMessage(*offset >= 0, "offset", "validation.min", 0)
+	})
+	if err != nil {
+		return err
+	}
+	f.Limit, f.Offset = int32(*limit), int32(*offset)
+
+	bids, err := a.models.Bids.ListBids(f)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(bids))
+	for _, b := range bids {
+		rows = append(rows, []string{b.Id, b.Name, b.Status, strconv.Itoa(b.Version), b.TenderId, b.AuthorType, b.AuthorId, b.CreatedAt})
+	}
+	return a.print(bids, []string{"ID", "NAME", "STATUS", "VERSION", "TENDER", "AUTHOR TYPE", "AUTHOR", "CREATED"}, rows)
+}
+
+type statusFlags struct {
+	id, status, note, actor string
+}
+
+func (a *adminTool) parseStatusFlags(name string, statuses []string, args []string) (statusFlags, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var f statusFlags
+	fs.StringVar(&f.id, "id", "", "id of the "+strings.TrimSuffix(name, "-status"))
+	fs.StringVar(&f.status, "status", "", "new status: "+strings.Join(statuses, ", "))
+	fs.StringVar(&f.note, "note", "", "why the status is forced, kept in the audit log")
+	fs.StringVar(&f.actor, "actor", "", "who forces the change, by default $USER")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("id", f.id, validator.Required(), validator.UUID())
+		v.Field("status", f.status, validator.Required(), validator.OneOf(statuses...))
+		v.Field("note", f.note, validator.Required(), validator.MaxRunes(400))
+		v.Field("actor", f.actor, validator.MaxRunes(100))
+	})
+	return f, err
+}
+
+// inTx runs fn in a transaction that is committed only if fn succeeds and
+// commit is true.
+func (a *adminTool) inTx(commit bool, fn func(tx *data.Tx) error) error {
+	tx, err := a.models.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !commit {
+		return tx.Rollback()
+	}
+	return tx.Commit()
+}
+
+func (a *adminTool) tenderStatus(args []string) error {
+	f, err := a.parseStatusFlags("tender-status", data.TenderStatuses, args)
+	if err != nil {
+		return err
+	}
+
+	var tender *data.Tender
+	err = a.inTx(true, func(tx *data.Tx) error {
+		old, err := tx.LockTender(f.id)
+		if err != nil {
+			return err
+		}
+		tender, err = tx.ChangeTenderStatus(f.id, f.status)
+		if err != nil {
+			return err
+		}
+		return tx.InsertAudit(&data.AuditEntry{
+			Actor:       a.actor(f.actor),
+			Action:      "tender-status",
+			SubjectType: "tender",
+			SubjectId:   f.id,
+			Note:        fmt.Sprintf("%s -> %s: %s", old.Status, f.status, f.note),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(tender, []string{"ID", "NAME", "STATUS", "VERSION"},
+		[][]string{{tender.Id, tender.Name, tender.Status, strconv.Itoa(tender.Version)}})
+}
+
+func (a *adminTool) bidStatus(args []string) error {
+	f, err := a.parseStatusFlags("bid-status", data.BidStatuses, args)
+	if err != nil {
+		return err
+	}
+
+	var bid *data.Bid
+	err = a.inTx(true, func(tx *data.Tx) error {
+		old, err := tx.LockBid(f.id)
+		if err != nil {
+			return err
+		}
+		bid, err = tx.ChangeBidStatus(f.id, f.status)
+		if err != nil {
+			return err
+		}
+		return tx.InsertAudit(&data.AuditEntry{
+			Actor:       a.actor(f.actor),
+			Action:      "bid-status",
+			SubjectType: "bid",
+			SubjectId:   f.id,
+			Note:        fmt.Sprintf("%s -> %s: %s", old.Status, f.status, f.note),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(bid, []string{"ID", "NAME", "STATUS", "VERSION"},
+		[][]string{{bid.Id, bid.Name, bid.Status, strconv.Itoa(bid.Version)}})
+}
+
+type quorumReport struct {
+	*data.QuorumStatus
+	Needed  int  `json:"needed"`
+	Reached bool `json:"reached"`
+	Closed  bool `json:"closed"`
+}
+
+// recomputeApprovals counts the approvals of the current version of every
+// published bid, as submitDecisionHandler does when one is added. Tenders
+// left open although a bid reached the quorum, for example after the
+// quorum was lowered, are closed with -apply.
+func (a *adminTool) recomputeApprovals(args []string) error {
+	fs := flag.NewFlagSet("recompute-approvals", flag.ContinueOnError)
+	tenderId := fs.String("tender", "", "only bids on this tender")
+	apply := fs.Bool("apply", false, "close the tenders that reached the quorum")
+	note := fs.String("note", "", "why the tenders are closed, required with -apply")
+	actor := fs.String("actor", "", "who closes the tenders, by default $USER")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("tender", *tenderId, validator.UUID())
+		if *apply {
+			v.Field("note", *note, validator.Required(), validator.MaxRunes(400))
+		}
+		v.Field("actor", *actor, validator.MaxRunes(100))
+	})
+	if err != nil {
+		return err
+	}
+
+	statuses, err := a.models.Bids.GetQuorumStatus(*tenderId)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]*quorumReport, 0, len(statuses))
+	closed := make(map[string]bool)
+	for _, s := range statuses {
+		report := &quorumReport{QuorumStatus: s, Needed: min(a.config.Approval.Quorum, s.Responsible)}
+		// An organization without responsibles needs no approvals at all,
+		// which must not count as a quorum.
+		report.Reached = report.Needed >= 1 && s.Approvals > 0 && s.Approvals >= report.Needed
+		reports = append(reports, report)
+
+		if !*apply || !report.Reached {
+			continue
+		}
+		if !closed[s.TenderId] {
+			err := a.inTx(true, func(tx *data.Tx) error {
+				if _, err := tx.ChangeTenderStatus(s.TenderId, "Closed"); err != nil {
+					return err
+				}
+				return tx.InsertAudit(&data.AuditEntry{
+					Actor:       a.actor(*actor),
+					Action:      "recompute-approvals",
+					SubjectType: "tender",
+					SubjectId:   s.TenderId,
+					Note:        fmt.Sprintf("bid %s has %d of %d approvals: %s", s.BidId, s.Approvals, report.Needed, *note),
+				})
+			})
+			if err != nil {
+				return err
+			}
+			closed[s.TenderId] = true
+		}
+		report.Closed = true
+	}
+
+	rows := make([][]string, 0, len(reports))
+	for _, r := range reports {
+		rows = append(rows, []string{r.TenderId, r.BidId, strconv.Itoa(r.BidVersion), strconv.Itoa(r.Approvals), strconv.Itoa(r.Needed), strconv.FormatBool(r.Reached), strconv.FormatBool(r.Closed)})
+	}
+	return a.print(reports, []string{"TENDER", "BID", "VERSION", "APPROVALS", "NEEDED", "REACHED", "CLOSED"}, rows)
+}
+
+// purgeHistory deletes old versions inside a transaction that is rolled
+// back unless -apply is given, so that a plain run reports what would go.
+func (a *adminTool) purgeHistory(args []string) error {
+	fs := flag.NewFlagSet("purge-history", flag.ContinueOnError)
+	keep := fs.Int("keep", 0, "number of versions before the current one to keep")
+	apply := fs.Bool("apply", false, "delete the versions instead of only counting them")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.CheckMessage(*keep >= 1, "keep", "validation.min", 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Tenders int64 `json:"tenderVersions"`
+		Bids    int64 `json:"bidVersions"`
+		Applied bool  `json:"applied"`
+	}
+	err = a.inTx(*apply, func(tx *data.Tx) error {
+		var err error
+		res.Tenders, res.Bids, err = tx.PurgeHistory(*keep)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	res.Applied = *apply
+	return a.print(res, []string{"TENDER VERSIONS", "BID VERSIONS", "APPLIED"},
+		[][]string{{strconv.FormatInt(res.Tenders, 10), strconv.FormatInt(res.Bids, 10), strconv.FormatBool(res.Applied)}})
+}
+
+func (a *adminTool) user(args []string) error {
+	fs := flag.NewFlagSet("user", flag.ContinueOnError)
+	username := fs.String("username", "", "user to show")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("username", *username, validator.Required())
+	})
+	if err != nil {
+		return err
+	}
+
+	user, err := a.models.Tenders.GetUserMemberships(*username)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(user.Organizations))
+	for _, m := range user.Organizations {
+		rows = append(rows, []string{user.UserId, user.Username, m.OrganizationId, m.OrganizationName})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{user.UserId, user.Username, "-", "-"})
+	}
+	return a.print(user, []string{"USER ID", "USERNAME", "ORGANIZATION ID", "ORGANIZATION"}, rows)
+}
+
+func (a *adminTool) audit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	subject := fs.String("subject", "", "only notes about this tender or bid")
+	limit := fs.Int("limit", 50, "maximum number of notes, 0 for all")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.Field("subject", *subject, validator.UUID())
+		v.CheckMessage(*limit >= 0, "limit", "validation.min", 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	entries, err := a.models.Audit.GetEntries(*subject, int32(*limit))
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []string{e.CreatedAt, e.Actor, e.Action, e.SubjectType, e.SubjectId, e.Note})
+	}
+	return a.print(entries, []string{"CREATED", "ACTOR", "ACTION", "TYPE", "SUBJECT", "NOTE"}, rows)
+}
+
+func (a *adminTool) migrate(args []string) error {
+	if err := a.parse(flag.NewFlagSet("migrate", flag.ContinueOnError), args, func(*validator.Validator) {}); err != nil {
+		return err
+	}
+	if err := a.models.Tables.CreateTables(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(a.stdout, "schema is at version %d\n", data.SchemaVersion())
+	return err
+}
+
+// seed creates the deterministic demo data of internal/seed, including the
+// platform tables on an empty database.
+func (a *adminTool) seed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	opts := seed.DefaultOptions()
+	opts.Quorum = a.config.Approval.Quorum
+	fs.Int64Var(&opts.Seed, "seed", opts.Seed, "seed value; the same value always gives the same data")
+	fs.IntVar(&opts.Organizations, "organizations", opts.Organizations, "number of organizations")
+	fs.IntVar(&opts.Employees, "employees", opts.Employees, "number of employees of each organization")
+	fs.IntVar(&opts.Tenders, "tenders", opts.Tenders, "number of tenders of each organization")
+	fs.IntVar(&opts.Bids, "bids", opts.Bids, "number of bids on each published or closed tender")
+	err := a.parse(fs, args, func(v *validator.Validator) {
+		v.CheckMessage(opts.Organizations >= 2, "organizations", "validation.min", 2)
+		v.CheckMessage(opts.Employees >= 1, "employees", "validation.min", 1)
+		v.CheckMessage(opts.Tenders >= 0, "tenders", "validation.min", 0)
+		v.CheckMessage(opts.Bids >= 0, "bids", "validation.min", 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Seeding is meant for empty databases, which lack the platform tables
+	// the migrations refer to.
+	if err := seed.Prepare(a.models); err != nil {
+		return err
+	}
+	res, err := seed.Run(a.models, opts)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.print(res, nil, nil)
+	}
+
+	rows := make([][]string, 0, len(res.Employees))
+	for _, organization := range res.Organizations {
+		for _, userId := range res.Members[organization.Id] {
+			i := slices.IndexFunc(res.Employees, func(e *data.Employee) bool { return e.Id == userId })
+			rows = append(rows, []string{organization.Name, organization.Id, res.Employees[i].Username, userId})
+		}
+	}
+	if err := a.print(res, []string{"ORGANIZATION", "ORGANIZATION ID", "USERNAME", "USER ID"}, rows); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.stdout, "\n%d tenders and %d bids created\n", len(res.Tenders), len(res.Bids))
+	return err
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// canReviewBid reports whether the user may see the approval state of a bid:
+// either as one of its responsibles or as a responsible of the tender's
+// organization.
+func (app *application) canReviewBid(userId string, bid *data.Bid) (bool, error) {
+	validIds, err := app.bidResponsibleIds(userId)
+	if err != nil {
+		return false, err
+	}
+	if containsString(validIds, bid.AuthorId) {
+		return true, nil
+	}
+
+	tenderOrganizationId, err := app.models.Tenders.GetTenderOrganization(bid.TenderId)
+	if err != nil {
+		return false, err
+	}
+	return containsString(validIds, tenderOrganizationId), nil
+}
+
+// reviewableBid loads the bid of the request for a user who may see its
+// approval state, answering with the error itself otherwise.
+func (app *application) reviewableBid(w http.ResponseWriter, r *http.Request) (*data.Bid, bool) {
+	q := r.URL.Query()
+	v := validator.New()
+	bidId := mux.Vars(r)["bidId"]
+	if _, err := uuid.Parse(bidId); err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
+		return nil, false
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return nil, false
+	}
+
+	bid, err := app.models.Bids.GetBidById(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
+			return nil, false
+		}
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return nil, false
+	}
+
+	allowed, err := app.canReviewBid(userId, bid)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return nil, false
+		}
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+	if !allowed {
+		app.forbiddenResponse(w, r, data.ErrNoRights)
+		return nil, false
+	}
+
+	return bid, true
+}
+
+// bidApprovalVersionsHandler shows how many approvals every version of a bid
+// has collected. It is open to the bid's responsibles and to the responsibles
+// of the tender's organization.
+func (app *application) bidApprovalVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	bid, ok := app.reviewableBid(w, r)
+	if !ok {
+		return
+	}
+
+	versions, err := app.models.Bids.ApprovalsByVersion(bid.Id)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, versions, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getBidApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	bid, ok := app.reviewableBid(w, r)
+	if !ok {
+		return
+	}
+
+	approvals, err := app.models.Bids.GetApprovals(bid.Id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, approvals, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// revokeApprovalHandler withdraws the user's approval of the current bid
+// version. Decisions on closed tenders are final and cannot be revoked.
+func (app *application) revokeApprovalHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	vars := mux.Vars(r)
+	bidId := vars["bidId"]
+	_, err := uuid.Parse(bidId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	bid, err := app.models.Bids.GetBidById(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	tenderStatus, err := app.models.Tenders.GetTenderStatus(bid.TenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if tenderStatus != "Published" {
+		app.forbiddenResponse(w, r, ErrTenderInactive)
+		return
+	}
+
+	err = app.models.Bids.RevokeApproval(bidId, userId)
+	if err != nil {
+		if errors.Is(err, data.ErrApprovalNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getPendingDecisionsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	decisions, err := app.models.Bids.GetPendingDecisions(limit, offset, userId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, decisions, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -2,10 +2,9 @@ package main
 
 import (
 	"avitotask/internal/data"
+	"avitotask/internal/validator"
 	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,36 +21,44 @@ type BidInput struct {
 	AuthorId    string `json:"authorId"`
 }
 
-var availableBidStatuses = map[string]bool{
-	"Created":   true,
-	"Published": true,
-	"Canceled":  true,
+type bidEditInput struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
-func tryGetBidStatusQuery(value []string) (string, error) {
-	if len(value) != 1 {
-		return "", errors.New("there can only be 1 status in request")
-	}
+func bidInputRules() validator.FieldRules {
+	rules := data.BidRules()
+	rules["tenderId"] = []validator.Rule{validator.Required(), validator.UUID()}
+	rules["authorType"] = []validator.Rule{validator.Required(), validator.OneOf("User", "Organization")}
+	rules["authorId"] = []validator.Rule{validator.Required(), validator.UUID()}
+	return rules
+}
 
-	for _, s := range value {
-		if _, ok := availableBidStatuses[s]; !ok {
-			return "", ErrWrongStatus(s)
-		}
-	}
+func bidEditInputRules() validator.FieldRules {
+	return data.BidRules().Optional()
+}
 
-	return string(value[0]), nil
+func (bid BidInput) validate(v *validator.Validator) {
+	v.Struct(bid, bidInputRules())
 }
 
-func tryGetDecisionQuery(value []string) (string, error) {
-	if len(value) != 1 {
-		return "", errors.New("there can only be 1 decision in request")
-	}
+func (bid bidEditInput) validate(v *validator.Validator) {
+	v.Struct(bid, bidEditInputRules())
+}
 
-	if value[0] == "Approved" || value[0] == "Rejected" {
-		return value[0], nil
-	}
+var availableBidStatuses = map[string]bool{
+	"Created":   true,
+	"Published": true,
+	"Canceled":  true,
+}
 
-	return "", errors.New("incorrect decision")
+// bidStatuses also lists statuses that are only reachable through dedicated
+// actions, not through the status endpoint.
+var bidStatuses = map[string]bool{
+	"Created":   true,
+	"Published": true,
+	"Canceled":  true,
+	"Withdrawn": true,
 }
 
 func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request) {
@@ -60,46 +67,38 @@ func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request)
 	err := readJSON(w, r, &bidInput)
 
 	if err != nil {
-		badRequestResponse(w, r, err)
-		return
-	}
-
-	if bidInput.Name == "" || bidInput.Description == "" || bidInput.TenderId == "" || bidInput.AuthorType == "" || bidInput.AuthorId == "" {
-		badRequestResponse(w, r, errors.New("all the values must be provided"))
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	if bidInput.AuthorType != "User" && bidInput.AuthorType != "Organization" {
-		badRequestResponse(w, r, errors.New("incorrect author type"))
-		return
-	}
-
-	if _, err := uuid.Parse(bidInput.TenderId); err != nil {
-		notFoundError(w, r, data.ErrTenderNotFound)
-		return
-	}
+	v := validator.New()
 
-	if _, err := uuid.Parse(bidInput.AuthorId); err != nil {
-		unauthorizedResponse(w, r, err)
+	if bidInput.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
-
 	}
 
 	tender, err := app.models.Tenders.GetTenderById(bidInput.TenderId)
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 	if tender.Status != "Published" {
-		forbiddenResponse(w, r, errors.New("trying to bid on tender that is not published"))
+		app.forbiddenResponse(w, r, ErrTenderNotPublished)
 		return
 	}
 
+	bidderIds := []string{bidInput.AuthorId}
+
+	// The author is the caller of this endpoint, so rate limits are charged
+	// to them.
+	authorUserId, authorOrganizationId := "", bidInput.AuthorId
+
 	if bidInput.AuthorType == "User" {
 		isInOrganization := true
 		organizationId, err := app.models.Tenders.GetUserOrganization(bidInput.AuthorId)
@@ -107,7 +106,7 @@ func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request)
 			if errors.Is(err, data.ErrOrganizationNotFound) {
 				isInOrganization = false
 			} else {
-				serverErrorResponse(w, r, err)
+				app.serverErrorResponse(w, r, err)
 				return
 			}
 		}
@@ -115,26 +114,51 @@ func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request)
 		//CHECK IF USER IN THE SAME ORGANIZATION AS TENDER
 
 		if isInOrganization && (organizationId == tender.OrganizationId) {
-			forbiddenResponse(w, r, errors.New("trying to bid on your own tender"))
+			app.forbiddenResponse(w, r, ErrSelfBid)
 			return
 		}
+		authorUserId, authorOrganizationId = bidInput.AuthorId, ""
+		if isInOrganization {
+			bidderIds = append(bidderIds, organizationId)
+			authorOrganizationId = organizationId
+		}
 	} else {
 		_, err = app.models.Tenders.GetOrganizationUsers(bidInput.AuthorId)
 		if err != nil {
 			if errors.Is(err, data.ErrUsernameNotFound) {
-				unauthorizedResponse(w, r, err)
+				app.unauthorizedResponse(w, r, err)
 				return
 			}
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 		if bidInput.AuthorId == tender.OrganizationId {
-			forbiddenResponse(w, r, errors.New("trying to bid on your own tender"))
+			app.forbiddenResponse(w, r, ErrSelfBid)
 			return
 		}
 
 	}
 
+	if !app.limitActor(w, r, authorUserId, authorOrganizationId) {
+		return
+	}
+
+	if !app.checkSupplier(w, r, tender, bidderIds) {
+		return
+	}
+
+	if tender.Visibility != data.VisibilityPublic {
+		invited, err := app.models.Invitations.IsInvited(tender.Id, bidderIds, true)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !invited {
+			app.forbiddenResponse(w, r, ErrNotInvited)
+			return
+		}
+	}
+
 	bid := data.Bid{
 		Id:          uuid.New().String(),
 		Description: bidInput.Description,
@@ -150,14 +174,14 @@ func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request)
 	err = app.models.Bids.InsertBid(&bid)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, bid, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
@@ -165,53 +189,17 @@ func (app *application) createBidHandler(w http.ResponseWriter, r *http.Request)
 
 func (app *application) getMyBidsHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		limit    int32
-		offset   int32
-		username string
-	}{
-		limit: 5,
-	}
-	limit, found := q["limit"]
-	if found {
-		parsedLimit, err := tryGetIntQuery(limit)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.limit = int32(parsedLimit)
-	}
-
-	offset, found := q["offset"]
-	if found {
-		parsedOffset, err := tryGetIntQuery(offset)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.offset = int32(parsedOffset)
-	}
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
+	v := validator.New()
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
 
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
@@ -221,7 +209,7 @@ func (app *application) getMyBidsHandler(w http.ResponseWriter, r *http.Request)
 		if errors.Is(err, data.ErrOrganizationNotFound) {
 			isInOrganization = false
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 
@@ -229,84 +217,57 @@ func (app *application) getMyBidsHandler(w http.ResponseWriter, r *http.Request)
 	var bids []*data.Bid
 
 	if isInOrganization {
-		bids, err = app.models.Bids.GetMyBids(params.limit, params.offset, organizationId, userId)
+		bids, err = app.models.Bids.GetMyBids(limit, offset, organizationId, userId)
 	} else {
-		bids, err = app.models.Bids.GetMyBids(params.limit, params.offset, NULL_UUID, userId)
+		bids, err = app.models.Bids.GetMyBids(limit, offset, NULL_UUID, userId)
 	}
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, bids, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
 
 func (app *application) changeBidStatusHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		status   string
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	bidId := vars["bidId"]
 	_, err := uuid.Parse(bidId)
 
 	if err != nil {
-		notFoundError(w, r, data.ErrBidNotFound)
+		app.notFoundError(w, r, data.ErrBidNotFound)
 		return
 	}
 
-	status, found := q["status"]
-	if found {
-		parsedStatus, err := tryGetBidStatusQuery(status)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.status = parsedStatus
-	} else {
-		badRequestResponse(w, r, errors.New("status must be provided"))
-		return
-	}
+	status := readStatusQuery(q, availableBidStatuses, v)
 
-	username, found := q["username"]
+	username := readUsernameQuery(q, v)
 
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	currentBid, err := app.models.Bids.GetBidById(bidId)
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	isInOrganization := true
@@ -316,7 +277,7 @@ func (app *application) changeBidStatusHandler(w http.ResponseWriter, r *http.Re
 		if errors.Is(err, data.ErrOrganizationNotFound) {
 			isInOrganization = false
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
@@ -324,7 +285,7 @@ func (app *application) changeBidStatusHandler(w http.ResponseWriter, r *http.Re
 	if isInOrganization {
 		validUsers, err := app.models.Tenders.GetOrganizationUsers(organizationId)
 		if err != nil {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 		validIds = append(validIds, organizationId)
@@ -332,62 +293,53 @@ func (app *application) changeBidStatusHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	if !containsString(validIds, currentBid.AuthorId) {
-		forbiddenResponse(w, r, errors.New("user is not responsible for this bid"))
+		app.forbiddenResponse(w, r, ErrNotBidResponsible)
+		return
+	}
+
+	if currentBid.Status == "Withdrawn" {
+		app.forbiddenResponse(w, r, ErrBidWithdrawn)
 		return
 	}
 
-	newBid, err := app.models.Bids.ChangeBidStatus(bidId, params.status)
+	newBid, err := app.models.Bids.ChangeBidStatus(bidId, status)
 
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		badRequestResponse(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 	err = writeJSON(w, r, http.StatusOK, newBid, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
 
 func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	bidId := vars["bidId"]
 	_, err := uuid.Parse(bidId)
 
 	if err != nil {
-		notFoundError(w, r, data.ErrBidNotFound)
+		app.notFoundError(w, r, data.ErrBidNotFound)
 		return
 	}
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
@@ -398,7 +350,7 @@ func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Reque
 		if errors.Is(err, data.ErrOrganizationNotFound) {
 			isInOrganization = false
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
@@ -406,7 +358,7 @@ func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Reque
 	if isInOrganization {
 		validUsers, err := app.models.Tenders.GetOrganizationUsers(organizationId)
 		if err != nil {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 		validIds = append(validIds, organizationId)
@@ -416,15 +368,15 @@ func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Reque
 	currentBid, err := app.models.Bids.GetBidById(bidId)
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if !containsString(validIds, currentBid.AuthorId) {
-		forbiddenResponse(w, r, errors.New("user is not responsible for this bid"))
+		app.forbiddenResponse(w, r, ErrNotBidResponsible)
 		return
 	}
 
@@ -432,10 +384,10 @@ func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Reque
 
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -444,55 +396,19 @@ func (app *application) getBidStatusHandler(w http.ResponseWriter, r *http.Reque
 
 func (app *application) getBidsForTenderHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		limit    int32
-		offset   int32
-		username string
-	}{
-		limit: 5,
-	}
+	v := validator.New()
 	vars := mux.Vars(r)
 	tenderId := vars["tenderId"]
-	limit, found := q["limit"]
-	if found {
-		parsedLimit, err := tryGetIntQuery(limit)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.limit = int32(parsedLimit)
-	}
-
-	offset, found := q["offset"]
-	if found {
-		parsedOffset, err := tryGetIntQuery(offset)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.offset = int32(parsedOffset)
-	}
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
 
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
@@ -500,10 +416,10 @@ func (app *application) getBidsForTenderHandler(w http.ResponseWriter, r *http.R
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			unauthorizedResponse(w, r, err)
+			app.unauthorizedResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
@@ -511,33 +427,33 @@ func (app *application) getBidsForTenderHandler(w http.ResponseWriter, r *http.R
 
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if userOrganizationId != tenderOrganizationId {
-		forbiddenResponse(w, r, errors.New("only users responsible for organizations can view bids"))
+		app.forbiddenResponse(w, r, ErrNotTenderResponsible)
 		return
 	}
 
-	bids, err := app.models.Bids.GetBidsByTenderId(params.limit, params.offset, tenderId)
+	bids, err := app.models.Bids.GetBidsByTenderId(limit, offset, tenderId)
 
 	if err != nil {
 		if errors.Is(err, data.ErrBidOrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, bids, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
@@ -545,47 +461,36 @@ func (app *application) getBidsForTenderHandler(w http.ResponseWriter, r *http.R
 
 func (app *application) updateBidHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		username string
-	}{}
+	v := validator.New()
 	vars := mux.Vars(r)
 	bidId := vars["bidId"]
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	_, err := uuid.Parse(bidId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
 		return
 	}
 
-	var input struct {
-		Name       string `json:"name"`
-		Desription string `json:"description"`
-	}
-	err := readJSON(w, r, &input)
+	username := readUsernameQuery(q, v)
+
+	var input bidEditInput
+	err = readJSON(w, r, &input)
 	if err != nil {
-		badRequestResponse(w, r, err)
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	bidInput := data.Bid{
 		Name:        input.Name,
-		Description: input.Desription,
+		Description: input.Description,
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
@@ -596,7 +501,7 @@ func (app *application) updateBidHandler(w http.ResponseWriter, r *http.Request)
 		if errors.Is(err, data.ErrOrganizationNotFound) {
 			isInOrganization = false
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
@@ -604,7 +509,7 @@ func (app *application) updateBidHandler(w http.ResponseWriter, r *http.Request)
 	if isInOrganization {
 		validUsers, err := app.models.Tenders.GetOrganizationUsers(organizationId)
 		if err != nil {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 		validIds = append(validIds, organizationId)
@@ -613,75 +518,59 @@ func (app *application) updateBidHandler(w http.ResponseWriter, r *http.Request)
 	currentBid, err := app.models.Bids.GetBidById(bidId)
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if !containsString(validIds, currentBid.AuthorId) {
-		forbiddenResponse(w, r, errors.New("user is not responsible for this bid"))
+		app.forbiddenResponse(w, r, ErrNotBidResponsible)
+		return
+	}
+
+	if currentBid.Status != "Created" && currentBid.Status != "Published" {
+		app.conflictResponse(w, r, ErrBidNotEditable)
 		return
 	}
 
 	updatedBid, err := app.models.Bids.EditBid(bidId, bidInput)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, updatedBid, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 }
 
 func (app *application) rollbackBidHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	bidId := vars["bidId"]
 	_, err := uuid.Parse(bidId)
 	if err != nil {
-		notFoundError(w, r, data.ErrBidNotFound)
+		app.notFoundError(w, r, data.ErrBidNotFound)
 		return
 	}
-	version, err := strconv.Atoi(vars["version"])
+	version := readVersionParam(vars, v)
+	username := readUsernameQuery(q, v)
 
-	if err != nil || version < 1 {
-		badRequestResponse(w, r, errors.New("version can only be an integer greater than 0"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	username, found := q["username"]
-
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
-		return
-	}
-
-	userId, err := app.models.Tenders.GetUserID(params.username)
-
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
@@ -692,7 +581,7 @@ func (app *application) rollbackBidHandler(w http.ResponseWriter, r *http.Reques
 		if errors.Is(err, data.ErrOrganizationNotFound) {
 			isInOrganization = false
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
@@ -700,7 +589,7 @@ func (app *application) rollbackBidHandler(w http.ResponseWriter, r *http.Reques
 	if isInOrganization {
 		validUsers, err := app.models.Tenders.GetOrganizationUsers(organizationId)
 		if err != nil {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 		validIds = append(validIds, organizationId)
@@ -710,15 +599,20 @@ func (app *application) rollbackBidHandler(w http.ResponseWriter, r *http.Reques
 	currentBid, err := app.models.Bids.GetBidById(bidId)
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if !containsString(validIds, currentBid.AuthorId) {
-		forbiddenResponse(w, r, errors.New("user is not responsible for this bid"))
+		app.forbiddenResponse(w, r, ErrNotBidResponsible)
+		return
+	}
+
+	if currentBid.Status != "Created" && currentBid.Status != "Published" {
+		app.conflictResponse(w, r, ErrBidNotEditable)
 		return
 	}
 
@@ -726,164 +620,462 @@ func (app *application) rollbackBidHandler(w http.ResponseWriter, r *http.Reques
 
 	if err != nil {
 		if errors.Is(err, data.ErrBidVersionNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, updatedBid, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 }
 
+// reviewer is a responsible of a tender organization submitting decisions,
+// resolved once per request.
+type reviewer struct {
+	userId            string
+	organizationId    string
+	organizationUsers int
+}
+
+func (app *application) reviewer(username string) (reviewer, *actionError) {
+	userId, err := app.models.Tenders.GetUserID(username)
+	if err != nil {
+		if errors.Is(err, data.ErrUsernameNotFound) {
+			return reviewer{}, actionFailed(http.StatusUnauthorized, err)
+		}
+		return reviewer{}, actionFailed(http.StatusInternalServerError, err)
+	}
+
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			return reviewer{}, actionFailed(http.StatusUnauthorized, err)
+		}
+		return reviewer{}, actionFailed(http.StatusInternalServerError, err)
+	}
+
+	users, err := app.models.Tenders.GetOrganizationUsers(organizationId)
+	if err != nil {
+		return reviewer{}, actionFailed(http.StatusInternalServerError, err)
+	}
+
+	return reviewer{userId: userId, organizationId: organizationId, organizationUsers: len(users)}, nil
+}
+
+// decideBid approves or rejects a published bid. The approval that reaches
+// the quorum closes the tender in the same transaction. The bid and the
+// tender stay locked until then, so that concurrent decisions cannot both
+// count the approvals before either is stored.
+func (app *application) decideBid(tx *data.Tx, reviewer reviewer, bidId, decision string) (*data.Bid, *actionError) {
+	bid, err := tx.LockBid(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			return nil, actionFailed(http.StatusNotFound, err)
+		}
+		return nil, actionFailed(http.StatusInternalServerError, err)
+	}
+	if bid.Status != "Published" {
+		return nil, actionFailed(http.StatusForbidden, ErrBidInactive)
+	}
+
+	tender, err := tx.LockTender(bid.TenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			return nil, actionFailed(http.StatusNotFound, err)
+		}
+		return nil, actionFailed(http.StatusInternalServerError, err)
+	}
+	if tender.Status != "Published" {
+		return nil, actionFailed(http.StatusForbidden, ErrTenderInactive)
+	}
+	if tender.OrganizationId != reviewer.organizationId {
+		return nil, actionFailed(http.StatusForbidden, ErrNotTenderResponsible)
+	}
+
+	if decision == "Rejected" {
+		newBid, err := tx.RejectDecision(bidId)
+		if err != nil {
+			if errors.Is(err, data.ErrBidNotFound) {
+				return nil, actionFailed(http.StatusNotFound, err)
+			}
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		return newBid, nil
+	}
+
+	approvers, err := tx.ApprovalCount(bidId)
+	if err != nil {
+		return nil, actionFailed(http.StatusInternalServerError, err)
+	}
+	if containsString(approvers, reviewer.userId) {
+		return nil, actionFailed(http.StatusForbidden, ErrBidAlreadyApproved)
+	}
+	err = tx.ApproveDecision(bidId, reviewer.userId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			return nil, actionFailed(http.StatusNotFound, err)
+		}
+		if errors.Is(err, data.ErrApprovalExists) {
+			return nil, actionFailed(http.StatusForbidden, ErrBidAlreadyApproved)
+		}
+		return nil, actionFailed(http.StatusInternalServerError, err)
+	}
+	if len(approvers)+1 >= app.config.Approval.Quorum || len(approvers)+1 >= reviewer.organizationUsers {
+		_, err := tx.ChangeTenderStatus(tender.Id, "Closed")
+		if err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+	}
+	return bid, nil
+}
+
 func (app *application) submitDecisionHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		username string
-		decision string
-	}{}
+	v := validator.New()
 	vars := mux.Vars(r)
 	bidId := vars["bidId"]
 	_, err := uuid.Parse(bidId)
 	if err != nil {
-		notFoundError(w, r, data.ErrBidNotFound)
+		app.notFoundError(w, r, data.ErrBidNotFound)
+		return
 	}
 
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	username := readUsernameQuery(q, v)
+	decision := readQueryValue(q, "decision", v)
+	v.Field("decision", decision, validator.Required(), validator.OneOf("Approved", "Rejected"))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
-	decision, found := q["decision"]
-	if found {
-		parsedDecision, err := tryGetDecisionQuery(decision)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
+
+	reviewer, e := app.reviewer(username)
+	if e != nil {
+		app.actionErrorResponse(w, r, e)
+		return
+	}
+	if !app.limitActor(w, r, reviewer.userId, reviewer.organizationId) {
+		return
+	}
+
+	tx, err := app.models.Begin()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	bid, e := app.decideBid(tx, reviewer, bidId, decision)
+	if e != nil {
+		tx.Rollback()
+		app.actionErrorResponse(w, r, e)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, bid, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// bidResponsibleIds returns the author ids a user may act for: the user, and
+// the organization with all of its responsibles if the user belongs to one.
+func (app *application) bidResponsibleIds(userId string) ([]string, error) {
+	validIds := []string{userId}
+
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			return validIds, nil
 		}
-		params.decision = parsedDecision
-	} else {
-		badRequestResponse(w, r, errors.New("decision must be provided"))
+		return nil, err
+	}
+
+	validUsers, err := app.models.Tenders.GetOrganizationUsers(organizationId)
+	if err != nil {
+		return nil, err
+	}
+	validIds = append(validIds, organizationId)
+	validIds = append(validIds, validUsers...)
+
+	return validIds, nil
+}
+
+func (app *application) withdrawBidHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	vars := mux.Vars(r)
+	bidId := vars["bidId"]
+	_, err := uuid.Parse(bidId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+	override := readQueryValue(q, "override", v)
+	v.Field("override", override, validator.OneOf("true", "false"))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
 	bid, err := app.models.Bids.GetBidById(bidId)
 	if err != nil {
 		if errors.Is(err, data.ErrBidNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
-	if bid.Status != "Published" {
-		forbiddenResponse(w, r, errors.New("trying to send decision on inactive bid"))
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
+	tender, err := app.models.Tenders.GetTenderById(bid.TenderId)
 	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
-	if err != nil {
-		if errors.Is(err, data.ErrOrganizationNotFound) {
-			unauthorizedResponse(w, r, err)
+	if tender.DeadlinePassed(time.Now()) {
+		// Past the deadline the tender owner decides whether a bid may leave.
+		if override != "true" {
+			app.forbiddenResponse(w, r, ErrDeadlinePassed)
+			return
+		}
+		organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+		if err != nil && !errors.Is(err, data.ErrOrganizationNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if organizationId != tender.OrganizationId {
+			app.forbiddenResponse(w, r, ErrNotTenderResponsible)
+			return
+		}
+	} else {
+		validIds, err := app.bidResponsibleIds(userId)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !containsString(validIds, bid.AuthorId) {
+			app.forbiddenResponse(w, r, ErrNotBidResponsible)
 			return
 		}
-		serverErrorResponse(w, r, err)
-		return
 	}
 
-	tender, err := app.models.Tenders.GetTenderById(bid.TenderId)
+	if bid.Status != "Created" && bid.Status != "Published" {
+		app.forbiddenResponse(w, r, ErrBidNotWithdrawable)
+		return
+	}
 
+	withdrawnBid, err := app.models.Bids.WithdrawBid(bidId)
 	if err != nil {
-		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
-	if tender.Status != "Published" {
-		forbiddenResponse(w, r, errors.New("user trying to send decision for inactive tender"))
+	err = writeJSON(w, r, http.StatusOK, withdrawnBid, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
+}
 
-	if tender.OrganizationId != userOrganizationId {
-		forbiddenResponse(w, r, errors.New("user trying to send decision for tender he is not responsible for"))
+func (app *application) resubmitBidHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	vars := mux.Vars(r)
+	bidId := vars["bidId"]
+	_, err := uuid.Parse(bidId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
 		return
 	}
 
-	if params.decision == "Approved" {
-		approvers, err := app.models.Bids.ApprovalCount(bidId)
-		if err != nil {
-			serverErrorResponse(w, r, err)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	bid, err := app.models.Bids.GetBidById(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
 			return
 		}
-		if containsString(approvers, userId) {
-			forbiddenResponse(w, r, errors.New("user has already approved"))
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	validIds, err := app.bidResponsibleIds(userId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !containsString(validIds, bid.AuthorId) {
+		app.forbiddenResponse(w, r, ErrNotBidResponsible)
+		return
+	}
+
+	if bid.Status != "Withdrawn" {
+		app.forbiddenResponse(w, r, ErrBidNotWithdrawn)
+		return
+	}
+
+	tender, err := app.models.Tenders.GetTenderById(bid.TenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
 			return
 		}
-		users, err := app.models.Tenders.GetOrganizationUsers(userOrganizationId)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if tender.Status != "Published" {
+		app.forbiddenResponse(w, r, ErrTenderNotPublished)
+		return
+	}
+	if tender.DeadlinePassed(time.Now()) {
+		app.forbiddenResponse(w, r, ErrDeadlinePassed)
+		return
+	}
+
+	bidderIds := []string{bid.AuthorId}
+	if bid.AuthorType == "User" {
+		bidderIds, err = app.viewerIds(bid.AuthorId)
 		if err != nil {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
-		err = app.models.Bids.ApproveDecision(bidId, userId)
-		if err != nil {
-			serverErrorResponse(w, r, err)
+	}
+	if !app.checkSupplier(w, r, tender, bidderIds) {
+		return
+	}
+
+	resubmittedBid, err := app.models.Bids.ResubmitBid(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
 			return
 		}
-		fmt.Println(len(approvers))
-		if len(approvers)+1 >= 3 || len(approvers)+1 >= len(users) {
-			_, err := app.models.Tenders.ChangeTenderStatus(tender.Id, "Closed")
-			if err != nil {
-				serverErrorResponse(w, r, err)
-				return
-			}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, resubmittedBid, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// bidDetail is a bid as seen by one viewer. Description is only filled in
+// for the bid's responsibles.
+type bidDetail struct {
+	data.Bid
+	Description string `json:"description,omitempty"`
+	TenderId    string `json:"tenderId"`
+	Approvals   int    `json:"approvals"`
+}
 
+// getBidHandler shows a single bid to its responsibles, and its published
+// bids to the responsibles of the tender's organization.
+func (app *application) getBidHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	bidId := mux.Vars(r)["bidId"]
+	_, err := uuid.Parse(bidId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrBidNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	bid, err := app.models.Bids.GetBidById(bidId)
+	if err != nil {
+		if errors.Is(err, data.ErrBidNotFound) {
+			app.notFoundError(w, r, err)
+			return
 		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	validIds, err := app.bidResponsibleIds(userId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
+	detail := bidDetail{Bid: *bid, TenderId: bid.TenderId}
+
+	if containsString(validIds, bid.AuthorId) {
+		detail.Description = bid.Description
 	} else {
-		newBid, err := app.models.Bids.RejectDecision(bidId)
+		tenderOrganizationId, err := app.models.Tenders.GetTenderOrganization(bid.TenderId)
 		if err != nil {
-			if errors.Is(err, data.ErrBidNotFound) {
-				notFoundError(w, r, err)
-				return
-			}
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
-		err = writeJSON(w, r, http.StatusOK, newBid, nil)
-		if err != nil {
-			serverErrorResponse(w, r, err)
+		if !containsString(validIds, tenderOrganizationId) || bid.Status != "Published" {
+			app.forbiddenResponse(w, r, ErrNotBidResponsible)
 			return
 		}
-		return
 	}
 
-	err = writeJSON(w, r, http.StatusOK, bid, nil)
+	approvals, err := app.models.Bids.ApprovalCount(bidId)
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
+		return
 	}
+	detail.Approvals = len(approvals)
 
+	err = writeJSON(w, r, http.StatusOK, detail, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 }
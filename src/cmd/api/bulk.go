@@ -0,0 +1,454 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/i18n"
+	"avitotask/internal/validator"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	bulkAtomic  = "atomic"
+	bulkPartial = "partial"
+
+	maxBulkItems = 500
+	maxBulkBytes = 8 << 20
+)
+
+var (
+	ErrBulkEmpty    = errors.New("bulk request contains no items")
+	ErrBulkTooLarge = errors.New("bulk request contains too many items")
+	errRolledBack   = errors.New("item succeeded but the transaction was rolled back")
+	errNotProcessed = errors.New("item was not processed because an earlier item failed")
+)
+
+// actionError is a failed step of an action that can run on its own or as
+// an item of a bulk request. Status is the HTTP status the step maps to;
+// validation failures carry the validator instead.
+type actionError struct {
+	status int
+	err    error
+	v      *validator.Validator
+}
+
+func actionFailed(status int, err error) *actionError {
+	return &actionError{status: status, err: err}
+}
+
+func actionInvalid(v *validator.Validator) *actionError {
+	return &actionError{status: http.StatusUnprocessableEntity, v: v}
+}
+
+// actionErrorResponse answers a single request that failed with e.
+func (app *application) actionErrorResponse(w http.ResponseWriter, r *http.Request, e *actionError) {
+	switch {
+	case e.v != nil:
+		app.failedValidationResponse(w, r, e.v)
+	case e.status == http.StatusUnauthorized:
+		app.unauthorizedResponse(w, r, e.err)
+	case e.status == http.StatusForbidden:
+		app.forbiddenResponse(w, r, e.err)
+	case e.status == http.StatusNotFound:
+		app.notFoundError(w, r, e.err)
+	case e.status == http.StatusConflict:
+		app.conflictResponse(w, r, e.err)
+	default:
+		app.serverErrorResponse(w, r, e.err)
+	}
+}
+
+type bulkResult struct {
+	Index   int               `json:"index"`
+	Status  int               `json:"status"`
+	Code    string            `json:"code,omitempty"`
+	Reason  string            `json:"reason,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	Result  any               `json:"result,omitempty"`
+}
+
+type bulkResponse struct {
+	Mode      string       `json:"mode"`
+	DryRun    bool         `json:"dryRun,omitempty"`
+	Committed bool         `json:"committed"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []bulkResult `json:"results"`
+}
+
+var bulkFallbackCodes = map[int]string{
+	http.StatusBadRequest:   CodeBadRequest,
+	http.StatusUnauthorized: CodeUnauthorized,
+	http.StatusForbidden:    CodeForbidden,
+	http.StatusNotFound:     CodeNotFound,
+	http.StatusConflict:     CodeConflict,
+}
+
+// failedResult renders e the way the matching error response would, but as
+// one entry of a bulk result.
+func (app *application) failedResult(r *http.Request, index int, e *actionError) bulkResult {
+	lang := requestLanguage(r)
+	res := bulkResult{Index: index, Status: e.status}
+
+	switch {
+	case e.v != nil:
+		res.Code = CodeValidationFailed
+		res.Reason = i18n.T(lang, CodeValidationFailed)
+		res.Details = e.v.Localized(lang)
+	case e.status == http.StatusFailedDependency:
+		res.Code = errorCode(e.err, CodeBulkRolledBack)
+		res.Reason = reasonFor(r, res.Code, e.err)
+	case bulkFallbackCodes[e.status] != "":
+		res.Code = errorCode(e.err, bulkFallbackCodes[e.status])
+		res.Reason = reasonFor(r, res.Code, e.err)
+	default:
+		app.logError(r, slog.LevelError, fmt.Errorf("bulk item %d: %w", index, e.err))
+		res.Status = http.StatusInternalServerError
+		res.Code = CodeInternal
+		res.Reason = i18n.T(lang, CodeInternal)
+	}
+	return res
+}
+
+func readBulkMode(q map[string][]string, v *validator.Validator) string {
+	mode := readQueryValue(q, "mode", v)
+	v.Field("mode", mode, validator.OneOf(bulkAtomic, bulkPartial))
+	if mode == "" {
+		mode = bulkPartial
+	}
+	return mode
+}
+
+// runBulk applies item to every index. In atomic mode all items share one
+// transaction that is only committed if every item succeeds; in partial
+// mode each item is committed on its own. A dry run handles every item in
+// its own transaction and rolls it back, so that each item reports its own
+// error whatever the mode.
+func (app *application) runBulk(r *http.Request, mode string, dryRun bool, n int, item func(tx *data.Tx, index int) (any, *actionError)) (*bulkResponse, error) {
+	res := &bulkResponse{Mode: mode, DryRun: dryRun, Results: make([]bulkResult, 0, n)}
+
+	if mode == bulkPartial || dryRun {
+		for i := 0; i < n; i++ {
+			tx, err := app.models.Begin()
+			if err != nil {
+				return nil, err
+			}
+			result, e := item(tx, i)
+			if e == nil && dryRun {
+				tx.Rollback()
+			} else if e == nil {
+				if err := tx.Commit(); err != nil {
+					e = actionFailed(http.StatusInternalServerError, err)
+				}
+			} else {
+				tx.Rollback()
+			}
+
+			if e != nil {
+				res.Failed++
+				res.Results = append(res.Results, app.failedResult(r, i, e))
+				continue
+			}
+			res.Succeeded++
+			res.Results = append(res.Results, bulkResult{Index: i, Status: http.StatusOK, Result: result})
+		}
+		res.Committed = !dryRun && res.Succeeded > 0
+		return res, nil
+	}
+
+	tx, err := app.models.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	failed := -1
+	for i := 0; i < n; i++ {
+		result, e := item(tx, i)
+		if e != nil {
+			failed = i
+			res.Results = append(res.Results, app.failedResult(r, i, e))
+			break
+		}
+		res.Results = append(res.Results, bulkResult{Index: i, Status: http.StatusOK, Result: result})
+	}
+
+	if failed < 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		res.Committed = true
+		res.Succeeded = n
+		return res, nil
+	}
+
+	tx.Rollback()
+	for i := range res.Results[:failed] {
+		res.Results[i] = app.failedResult(r, i, actionFailed(http.StatusFailedDependency, errRolledBack))
+	}
+	for i := failed + 1; i < n; i++ {
+		res.Results = append(res.Results, app.failedResult(r, i, actionFailed(http.StatusFailedDependency, errNotProcessed)))
+	}
+	res.Failed = n
+	return res, nil
+}
+
+// readBulkItems reads the request body either as a JSON array or, when the
+// body does not start with '[', as newline-delimited JSON objects.
+func readBulkItems[T any](w http.ResponseWriter, r *http.Request) ([]T, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBulkBytes)
+	return decodeItems[T](r.Body, maxBulkItems)
+}
+
+// decodeItems reads at most max items from a JSON array or from
+// newline-delimited JSON objects.
+func decodeItems[T any](r io.Reader, max int) ([]T, error) {
+	body := bufio.NewReader(r)
+
+	var first byte
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, ErrBulkEmpty
+			}
+			return nil, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			first = b
+			body.UnreadByte()
+			break
+		}
+	}
+
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	var items []T
+	if first == '[' {
+		if err := dec.Decode(&items); err != nil {
+			return nil, fmt.Errorf("body contains badly-formed JSON array: %w", err)
+		}
+		if dec.More() {
+			return nil, errors.New("body must only contain a single JSON array")
+		}
+	} else {
+		for {
+			var item T
+			err := dec.Decode(&item)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", len(items)+1, err)
+			}
+			items = append(items, item)
+			if len(items) > max {
+				return nil, tooManyItems(max)
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, ErrBulkEmpty
+	}
+	if len(items) > max {
+		return nil, tooManyItems(max)
+	}
+	return items, nil
+}
+
+func tooManyItems(max int) error {
+	return fmt.Errorf("%w: the limit is %d", ErrBulkTooLarge, max)
+}
+
+func (app *application) writeBulkResponse(w http.ResponseWriter, r *http.Request, res *bulkResponse) {
+	err := writeJSON(w, r, http.StatusOK, res, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+type bulkStatusItem struct {
+	TenderId string `json:"tenderId"`
+	Status   string `json:"status"`
+}
+
+// bulkTenderStatusHandler changes the status of many tenders of the user's
+// organization with a single user lookup.
+func (app *application) bulkTenderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+	mode := readBulkMode(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	items, err := readBulkItems[bulkStatusItem](w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	res, err := app.runBulk(r, mode, false, len(items), func(tx *data.Tx, i int) (any, *actionError) {
+		item := items[i]
+
+		v := validator.New()
+		v.Field("tenderId", item.TenderId, validator.Required(), validator.UUID())
+		checkStatus(v, "status", item.Status, availableStatuses)
+		if !v.Valid() {
+			return nil, actionInvalid(v)
+		}
+
+		tender, err := tx.GetTenderById(item.TenderId)
+		if err != nil {
+			if errors.Is(err, data.ErrTenderNotFound) {
+				return nil, actionFailed(http.StatusNotFound, err)
+			}
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		if tender.OrganizationId != organizationId {
+			return nil, actionFailed(http.StatusForbidden, data.ErrNoRights)
+		}
+
+		tender, err = tx.ChangeTenderStatus(item.TenderId, item.Status)
+		if err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		return tender, nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeBulkResponse(w, r, res)
+}
+
+// bulkCreateTendersHandler creates tenders from a JSON array or NDJSON body.
+// Items may leave creatorUsername out; it defaults to the username
+// parameter and may not name anyone else.
+func (app *application) bulkCreateTendersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+	mode := readBulkMode(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	items, err := readBulkItems[tenderInput](w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	res, err := app.runBulk(r, mode, false, len(items), func(tx *data.Tx, i int) (any, *actionError) {
+		input := items[i]
+		if input.CreatorUsername == "" {
+			input.CreatorUsername = username
+		}
+
+		v := validator.New()
+		input.validate(v)
+		if err := app.checkServiceType(v, "serviceType", input.ServiceType); err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		if !v.Valid() {
+			return nil, actionInvalid(v)
+		}
+
+		if input.CreatorUsername != username || input.OrganizationID != organizationId {
+			return nil, actionFailed(http.StatusForbidden, data.ErrNoRights)
+		}
+
+		tender := input.tender()
+		err := tx.InsertTender(&tender)
+		if err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		return tender, nil
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeBulkResponse(w, r, res)
+}
+
+type bulkDecisionItem struct {
+	BidId    string `json:"bidId"`
+	Decision string `json:"decision"`
+}
+
+// bulkBidDecisionHandler submits decisions on many bids with a single
+// lookup of the reviewer.
+func (app *application) bulkBidDecisionHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+	mode := readBulkMode(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	items, err := readBulkItems[bulkDecisionItem](w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	reviewer, e := app.reviewer(username)
+	if e != nil {
+		app.actionErrorResponse(w, r, e)
+		return
+	}
+	if !app.limitActor(w, r, reviewer.userId, reviewer.organizationId) {
+		return
+	}
+
+	res, err := app.runBulk(r, mode, false, len(items), func(tx *data.Tx, i int) (any, *actionError) {
+		item := items[i]
+
+		v := validator.New()
+		v.Field("bidId", item.BidId, validator.Required(), validator.UUID())
+		v.Field("decision", item.Decision, validator.Required(), validator.OneOf("Approved", "Rejected"))
+		if !v.Valid() {
+			return nil, actionInvalid(v)
+		}
+
+		return app.decideBid(tx, reviewer, item.BidId, item.Decision)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeBulkResponse(w, r, res)
+}
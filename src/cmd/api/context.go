@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const requestIDContextKey = contextKey("requestID")
+
+func contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+func contextGetRequestID(r *http.Request) string {
+	requestID, ok := r.Context().Value(requestIDContextKey).(string)
+	if !ok {
+		return ""
+	}
+	return requestID
+}
+
+const rateLimitContextKey = contextKey("rateLimit")
+
+func contextSetRateLimit(r *http.Request, scope *rateLimitScope) *http.Request {
+	ctx := context.WithValue(r.Context(), rateLimitContextKey, scope)
+	return r.WithContext(ctx)
+}
+
+func contextGetRateLimit(r *http.Request) *rateLimitScope {
+	scope, _ := r.Context().Value(rateLimitContextKey).(*rateLimitScope)
+	return scope
+}
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"avitotask/internal/data"
+	"avitotask/internal/i18n"
+	"avitotask/internal/validator"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 type ErrWrongService string
@@ -18,30 +25,292 @@ func (err ErrWrongStatus) Error() string {
 	return fmt.Sprintf("status %s does not exist", string(err))
 }
 
-func errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	writeJSON(w, r, status, envelope{"reason": message}, nil)
+var (
+	ErrTenderNotPublished   = errors.New("trying to bid on tender that is not published")
+	ErrTenderInactive       = errors.New("user trying to send decision for inactive tender")
+	ErrSelfBid              = errors.New("trying to bid on your own tender")
+	ErrBidInactive          = errors.New("trying to send decision on inactive bid")
+	ErrBidAlreadyApproved   = errors.New("user has already approved")
+	ErrNotBidResponsible    = errors.New("user is not responsible for this bid")
+	ErrNotTenderResponsible = errors.New("user is not responsible for this tender")
+	ErrBidNotWithdrawable   = errors.New("only created or published bids can be withdrawn")
+	ErrBidNotWithdrawn      = errors.New("only withdrawn bids can be resubmitted")
+	ErrBidWithdrawn         = errors.New("bid is withdrawn and can only be resubmitted")
+	ErrBidNotEditable       = errors.New("only created or published bids can be edited")
+	ErrDeadlinePassed       = errors.New("tender deadline has passed")
+	ErrNotInvited           = errors.New("bidder has no accepted invitation to this tender")
+	ErrNotInvitee           = errors.New("invitation is addressed to someone else")
+	ErrSupplierBlocked      = errors.New("bidder is on the blocklist of the tender organization")
+	ErrSupplierNotQualified = errors.New("tender requires a qualified supplier")
+	ErrQualificationExpired = errors.New("supplier qualification has expired")
+)
+
+// Error codes are part of the API contract: clients match on them, so
+// existing values must never change.
+const (
+	CodeBadRequest            = "BAD_REQUEST"
+	CodeValidationFailed      = "VALIDATION_FAILED"
+	CodeUnauthorized          = "UNAUTHORIZED"
+	CodeForbidden             = "FORBIDDEN"
+	CodeNotFound              = "NOT_FOUND"
+	CodeRateLimited           = "RATE_LIMITED"
+	CodeInternal              = "INTERNAL_ERROR"
+	CodeUserNotFound          = "USER_NOT_FOUND"
+	CodeOrganizationNotFound  = "ORGANIZATION_NOT_FOUND"
+	CodeNoRights              = "NO_RIGHTS"
+	CodeServiceTypeUnknown    = "SERVICE_TYPE_UNKNOWN"
+	CodeStatusUnknown         = "STATUS_UNKNOWN"
+	CodeTenderNotFound        = "TENDER_NOT_FOUND"
+	CodeTenderVersionNotFound = "TENDER_VERSION_NOT_FOUND"
+	CodeTenderNotPublished    = "TENDER_NOT_PUBLISHED"
+	CodeTenderInactive        = "TENDER_INACTIVE"
+	CodeNotTenderResponsible  = "NOT_TENDER_RESPONSIBLE"
+	CodeBidNotFound           = "BID_NOT_FOUND"
+	CodeBidOrTenderNotFound   = "BID_OR_TENDER_NOT_FOUND"
+	CodeBidVersionNotFound    = "BID_VERSION_NOT_FOUND"
+	CodeBidInactive           = "BID_INACTIVE"
+	CodeBidAlreadyApproved    = "BID_ALREADY_APPROVED"
+	CodeSelfBidForbidden      = "SELF_BID_FORBIDDEN"
+	CodeNotBidResponsible     = "NOT_BID_RESPONSIBLE"
+	CodeBidNotWithdrawable    = "BID_NOT_WITHDRAWABLE"
+	CodeBidNotWithdrawn       = "BID_NOT_WITHDRAWN"
+	CodeBidWithdrawn          = "BID_WITHDRAWN"
+	CodeBidNotEditable        = "BID_NOT_EDITABLE"
+	CodeDeadlinePassed        = "TENDER_DEADLINE_PASSED"
+	CodeApprovalNotFound      = "APPROVAL_NOT_FOUND"
+	CodeTemplateNotFound      = "TEMPLATE_NOT_FOUND"
+	CodeConflict              = "CONFLICT"
+	CodeServiceTypeExists     = "SERVICE_TYPE_EXISTS"
+	CodeServiceTypeInUse      = "SERVICE_TYPE_IN_USE"
+	CodeServiceTypeParent     = "SERVICE_TYPE_PARENT_INVALID"
+	CodeNotInvited            = "NOT_INVITED"
+	CodeNotInvitee            = "NOT_INVITEE"
+	CodeInvitationNotFound    = "INVITATION_NOT_FOUND"
+	CodeInvitationExists      = "INVITATION_EXISTS"
+	CodeInviteeNotFound       = "INVITEE_NOT_FOUND"
+	CodeSupplierBlocked       = "SUPPLIER_BLOCKED"
+	CodeSupplierNotQualified  = "SUPPLIER_NOT_QUALIFIED"
+	CodeQualificationExpired  = "SUPPLIER_QUALIFICATION_EXPIRED"
+	CodeSupplierNotFound      = "SUPPLIER_NOT_FOUND"
+	CodeSupplierEntryNotFound = "SUPPLIER_ENTRY_NOT_FOUND"
+	CodeSupplierEntryExists   = "SUPPLIER_ENTRY_EXISTS"
+	CodeRequirementNotFound   = "QUALIFICATION_REQUIREMENT_NOT_FOUND"
+	CodeBulkEmpty             = "BULK_EMPTY"
+	CodeBulkTooLarge          = "BULK_TOO_LARGE"
+	CodeBulkRolledBack        = "BULK_ROLLED_BACK"
+	CodeBulkNotProcessed      = "BULK_NOT_PROCESSED"
+	CodeImportExists          = "IMPORT_EXISTS"
+	CodeIdempotencyKeyInvalid = "IDEMPOTENCY_KEY_INVALID"
+	CodeIdempotencyKeyReused  = "IDEMPOTENCY_KEY_REUSED"
+	CodeIdempotencyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+var errorCatalogue = []struct {
+	err  error
+	code string
+}{
+	{data.ErrUsernameNotFound, CodeUserNotFound},
+	{data.ErrNoRights, CodeNoRights},
+	{data.ErrOrganizationNotFound, CodeOrganizationNotFound},
+	{data.ErrTenderNotFound, CodeTenderNotFound},
+	{data.ErrTenderVersionNotFound, CodeTenderVersionNotFound},
+	{data.ErrBidNotFound, CodeBidNotFound},
+	{data.ErrBidOrTenderNotFound, CodeBidOrTenderNotFound},
+	{data.ErrBidVersionNotFound, CodeBidVersionNotFound},
+	{data.ErrApprovalNotFound, CodeApprovalNotFound},
+	{data.ErrTemplateNotFound, CodeTemplateNotFound},
+	{data.ErrServiceTypeNotFound, CodeServiceTypeUnknown},
+	{data.ErrServiceTypeExists, CodeServiceTypeExists},
+	{data.ErrServiceTypeInUse, CodeServiceTypeInUse},
+	{data.ErrServiceTypeParent, CodeServiceTypeParent},
+	{data.ErrInvitationNotFound, CodeInvitationNotFound},
+	{data.ErrInvitationExists, CodeInvitationExists},
+	{data.ErrSupplierEntryNotFound, CodeSupplierEntryNotFound},
+	{data.ErrSupplierEntryExists, CodeSupplierEntryExists},
+	{data.ErrRequirementNotFound, CodeRequirementNotFound},
+	{ErrNotInvited, CodeNotInvited},
+	{ErrNotInvitee, CodeNotInvitee},
+	{ErrBulkEmpty, CodeBulkEmpty},
+	{ErrBulkTooLarge, CodeBulkTooLarge},
+	{errRolledBack, CodeBulkRolledBack},
+	{errNotProcessed, CodeBulkNotProcessed},
+	{data.ErrImportExists, CodeImportExists},
+	{ErrIdempotencyKeyInvalid, CodeIdempotencyKeyInvalid},
+	{ErrIdempotencyKeyReused, CodeIdempotencyKeyReused},
+	{ErrIdempotencyKeyInProgress, CodeIdempotencyInProgress},
+	{ErrSupplierBlocked, CodeSupplierBlocked},
+	{ErrSupplierNotQualified, CodeSupplierNotQualified},
+	{ErrQualificationExpired, CodeQualificationExpired},
+	{ErrTenderNotPublished, CodeTenderNotPublished},
+	{ErrTenderInactive, CodeTenderInactive},
+	{ErrSelfBid, CodeSelfBidForbidden},
+	{ErrBidInactive, CodeBidInactive},
+	{ErrBidAlreadyApproved, CodeBidAlreadyApproved},
+	{ErrNotBidResponsible, CodeNotBidResponsible},
+	{ErrNotTenderResponsible, CodeNotTenderResponsible},
+	{ErrBidNotWithdrawable, CodeBidNotWithdrawable},
+	{ErrBidNotWithdrawn, CodeBidNotWithdrawn},
+	{ErrBidWithdrawn, CodeBidWithdrawn},
+	{ErrBidNotEditable, CodeBidNotEditable},
+	{ErrDeadlinePassed, CodeDeadlinePassed},
+}
+
+// errorCode maps err onto the catalogue, falling back to the generic code
+// of the response status.
+func errorCode(err error, fallback string) string {
+	for _, entry := range errorCatalogue {
+		if errors.Is(err, entry.err) {
+			return entry.code
+		}
+	}
+
+	var wrongService ErrWrongService
+	if errors.As(err, &wrongService) {
+		return CodeServiceTypeUnknown
+	}
+	var wrongStatus ErrWrongStatus
+	if errors.As(err, &wrongStatus) {
+		return CodeStatusUnknown
+	}
+
+	return fallback
+}
+
+// requestLanguage negotiates the response language from Accept-Language.
+func requestLanguage(r *http.Request) string {
+	return i18n.Negotiate(r.Header.Get("Accept-Language"))
 }
 
-func badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Println(err)
-	errorResponse(w, r, http.StatusBadRequest, err.Error())
+// reasonFor returns the catalogue message for code in the request language,
+// or the error text for codes that have no fixed wording.
+func reasonFor(r *http.Request, code string, err error) string {
+	lang := requestLanguage(r)
+
+	var wrongService ErrWrongService
+	if errors.As(err, &wrongService) {
+		return i18n.T(lang, "validation.unknownService", string(wrongService))
+	}
+	var wrongStatus ErrWrongStatus
+	if errors.As(err, &wrongStatus) {
+		return i18n.T(lang, "validation.unknownStatus", string(wrongStatus))
+	}
+
+	if message, ok := i18n.Lookup(lang, code); ok {
+		return message
+	}
+	if message, ok := i18n.Lookup(i18n.Default, code); ok {
+		return message
+	}
+	return err.Error()
 }
 
-func serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Println(err)
-	errorResponse(w, r, http.StatusInternalServerError, "the server encountered a problem and could not handle request")
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Reason    string `json:"reason"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
-func unauthorizedResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Println(err)
-	errorResponse(w, r, http.StatusUnauthorized, "username is incorrect or does not exist")
+func (app *application) logError(r *http.Request, level slog.Level, err error) {
+	attrs := []any{
+		slog.String("request_id", contextGetRequestID(r)),
+		slog.String("method", r.Method),
+		slog.String("uri", r.URL.RequestURI()),
+	}
+
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, tplErr := route.GetPathTemplate(); tplErr == nil {
+			attrs = append(attrs, slog.String("route", template))
+		}
+	}
+
+	if username := r.URL.Query().Get("username"); username != "" {
+		attrs = append(attrs, slog.String("user", username))
+	}
+
+	vars := mux.Vars(r)
+	if tenderId, ok := vars["tenderId"]; ok {
+		attrs = append(attrs, slog.String("tender_id", tenderId))
+	}
+	if bidId, ok := vars["bidId"]; ok {
+		attrs = append(attrs, slog.String("bid_id", bidId))
+	}
+
+	attrs = append(attrs, slog.String("error", err.Error()))
+	if cause := errors.Unwrap(err); cause != nil {
+		attrs = append(attrs, slog.String("cause", cause.Error()))
+	}
+
+	app.logger.Log(r.Context(), level, "request failed", attrs...)
 }
-func forbiddenResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Println(err)
-	errorResponse(w, r, http.StatusForbidden, "user does not have rights for this action")
+
+func errorResponse(w http.ResponseWriter, r *http.Request, status int, code, reason string, details any) {
+	body := problem{
+		Type:      "urn:problem:" + strings.ToLower(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Code:      code,
+		Reason:    reason,
+		Details:   details,
+		RequestID: contextGetRequestID(r),
+	}
+
+	headers := http.Header{
+		"Content-Type":     {"application/problem+json"},
+		"Content-Language": {requestLanguage(r)},
+	}
+	writeJSON(w, r, status, body, headers)
+}
+
+func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeBadRequest)
+	errorResponse(w, r, http.StatusBadRequest, code, reasonFor(r, code, err), nil)
+}
+
+func (app *application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelError, err)
+	errorResponse(w, r, http.StatusInternalServerError, CodeInternal, i18n.T(requestLanguage(r), CodeInternal), nil)
+}
+
+func (app *application) unauthorizedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeUnauthorized)
+	errorResponse(w, r, http.StatusUnauthorized, code, i18n.T(requestLanguage(r), CodeUnauthorized), nil)
+}
+func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeForbidden)
+	errorResponse(w, r, http.StatusForbidden, code, reasonFor(r, code, data.ErrNoRights), nil)
+}
+
+func (app *application) notFoundError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeNotFound)
+	errorResponse(w, r, http.StatusNotFound, code, reasonFor(r, code, err), nil)
+}
+
+func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeConflict)
+	errorResponse(w, r, http.StatusConflict, code, reasonFor(r, code, err), nil)
+}
+
+func (app *application) unprocessableResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logError(r, slog.LevelWarn, err)
+	code := errorCode(err, CodeValidationFailed)
+	errorResponse(w, r, http.StatusUnprocessableEntity, code, reasonFor(r, code, err), nil)
+}
+
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator) {
+	app.logError(r, slog.LevelInfo, fmt.Errorf("validation failed: %v", v.Errors))
+	lang := requestLanguage(r)
+	errorResponse(w, r, http.StatusUnprocessableEntity, CodeValidationFailed, i18n.T(lang, CodeValidationFailed), v.Localized(lang))
 }
 
-func notFoundError(w http.ResponseWriter, r *http.Request, err error) {
-	log.Println(err)
-	errorResponse(w, r, http.StatusNotFound, err.Error())
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
+	errorResponse(w, r, http.StatusTooManyRequests, CodeRateLimited, i18n.T(requestLanguage(r), CodeRateLimited), nil)
 }
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"avitotask/internal/data"
+	"avitotask/internal/export"
+	"avitotask/internal/validator"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	includeHistory   = "history"
+	includeApprovals = "approvals"
+
+	// exportFlushRows is how many rows are buffered before they are sent.
+	exportFlushRows = 200
+)
+
+func readExportFormat(q url.Values, v *validator.Validator) string {
+	format := readQueryValue(q, "format", v)
+	if format == "" {
+		return export.FormatCSV
+	}
+	v.Field("format", format, validator.OneOf(export.Formats...))
+	return format
+}
+
+// readExportOptions reads include, a comma separated list of optional
+// column groups.
+func readExportOptions(q url.Values, v *validator.Validator) data.ExportOptions {
+	var opts data.ExportOptions
+	include := readQueryValue(q, "include", v)
+	if include == "" {
+		return opts
+	}
+	for _, item := range strings.Split(include, ",") {
+		item = strings.TrimSpace(item)
+		v.Field("include", item, validator.OneOf(includeHistory, includeApprovals))
+		switch item {
+		case includeHistory:
+			opts.History = true
+		case includeApprovals:
+			opts.Approvals = true
+		}
+	}
+	return opts
+}
+
+// readExportPagination reads the paging of the list endpoints, except that
+// without a limit the whole list is exported.
+func readExportPagination(q url.Values, v *validator.Validator) (int32, int32) {
+	limit := readIntQuery(q, "limit", 0, 1, v)
+	offset := readIntQuery(q, "offset", 0, 0, v)
+	return int32(limit), int32(offset)
+}
+
+// exportStream sends a report as it is read from the database. Nothing is
+// written until the first row, so an error before it still gets a regular
+// error response.
+type exportStream struct {
+	w        http.ResponseWriter
+	rc       *http.ResponseController
+	format   string
+	filename string
+	header   []any
+	timeout  time.Duration
+	out      export.Writer
+	rows     int
+}
+
+func (app *application) newExportStream(w http.ResponseWriter, format, name string, header []any) *exportStream {
+	return &exportStream{
+		w:        w,
+		rc:       http.NewResponseController(w),
+		format:   format,
+		filename: name + "." + format,
+		header:   header,
+		timeout:  app.config.Server.WriteTimeout.Std(),
+	}
+}
+
+func (s *exportStream) start() error {
+	s.w.Header().Set("Content-Type", export.ContentType(s.format))
+	s.w.Header().Set("Content-Disposition", `attachment; filename="`+s.filename+`"`)
+	s.w.WriteHeader(http.StatusOK)
+
+	out, err := export.New(s.format, s.w)
+	if err != nil {
+		return err
+	}
+	s.out = out
+	s.extendDeadline()
+	return s.out.WriteRow(s.header...)
+}
+
+// extendDeadline keeps the server write timeout from cutting off a report
+// that is still making progress.
+func (s *exportStream) extendDeadline() {
+	if s.timeout > 0 {
+		_ = s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+}
+
+func (s *exportStream) row(cells ...any) error {
+	if s.out == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if err := s.out.WriteRow(cells...); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%exportFlushRows != 0 {
+		return nil
+	}
+	if err := s.out.Flush(); err != nil {
+		return err
+	}
+	s.extendDeadline()
+	return s.rc.Flush()
+}
+
+func (s *exportStream) finish() error {
+	if s.out == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.out.Close()
+}
+
+// exportFailed reports an error of a report. Once rows have been sent the
+// status cannot change, so the connection is dropped and the client sees a
+// truncated download instead of a report that looks complete.
+func (app *application) exportFailed(w http.ResponseWriter, r *http.Request, s *exportStream, err error) {
+	if s.out == nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.logError(r, slog.LevelError, err)
+	panic(http.ErrAbortHandler)
+}
+
+func tenderExportHeader(opts data.ExportOptions) []any {
+	header := []any{"id", "name", "description", "serviceType", "status", "visibility", "version", "createdAt", "deadline"}
+	if opts.History {
+		header = append(header, "current")
+	}
+	if opts.Approvals {
+		header = append(header, "bids", "approvals")
+	}
+	return header
+}
+
+func tenderExportCells(t *data.TenderExportRow, opts data.ExportOptions) []any {
+	cells := []any{t.Id, t.Name, t.Description, t.ServiceType, t.Status, t.Visibility, t.Version, t.CreatedAt, t.Deadline}
+	if opts.History {
+		cells = append(cells, t.Current)
+	}
+	if opts.Approvals {
+		cells = append(cells, t.Bids, t.Approvals)
+	}
+	return cells
+}
+
+func bidExportHeader(opts data.ExportOptions) []any {
+	header := []any{"id", "name", "status", "tenderId", "authorType", "authorId", "version", "createdAt"}
+	if opts.History {
+		header = append(header, "current")
+	}
+	if opts.Approvals {
+		header = append(header, "approvals", "approvers")
+	}
+	return header
+}
+
+// bidExportCells leaves out the description, which the bid list does not
+// show to the tender organization either.
+func bidExportCells(b *data.BidExportRow, opts data.ExportOptions) []any {
+	cells := []any{b.Id, b.Name, b.Status, b.TenderId, b.AuthorType, b.AuthorId, b.Version, b.CreatedAt}
+	if opts.History {
+		cells = append(cells, b.Current)
+	}
+	if opts.Approvals {
+		cells = append(cells, b.Approvals, strings.Join(b.Approvers, ";"))
+	}
+	return cells
+}
+
+func (app *application) exportMyTendersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	format := readExportFormat(q, v)
+	opts := readExportOptions(q, v)
+	limit, offset := readExportPagination(q, v)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			app.forbiddenResponse(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	s := app.newExportStream(w, format, "tenders", tenderExportHeader(opts))
+	err = app.models.Tenders.ExportMyTenders(r.Context(), limit, offset, organizationId, opts, func(t *data.TenderExportRow) error {
+		return s.row(tenderExportCells(t, opts)...)
+	})
+	if err == nil {
+		err = s.finish()
+	}
+	if err != nil {
+		app.exportFailed(w, r, s, err)
+	}
+}
+
+func (app *application) exportTenderBidsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	tenderId := mux.Vars(r)["tenderId"]
+	if _, err := uuid.Parse(tenderId); err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	format := readExportFormat(q, v)
+	opts := readExportOptions(q, v)
+	limit, offset := readExportPagination(q, v)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	tenderOrganizationId, err := app.models.Tenders.GetTenderOrganization(tenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			app.forbiddenResponse(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if userOrganizationId != tenderOrganizationId {
+		app.forbiddenResponse(w, r, ErrNotTenderResponsible)
+		return
+	}
+
+	s := app.newExportStream(w, format, "bids-"+tenderId, bidExportHeader(opts))
+	err = app.models.Bids.ExportTenderBids(r.Context(), limit, offset, tenderId, opts, func(b *data.BidExportRow) error {
+		return s.row(bidExportCells(b, opts)...)
+	})
+	if err == nil {
+		err = s.finish()
+	}
+	if err != nil {
+		app.exportFailed(w, r, s, err)
+	}
+}
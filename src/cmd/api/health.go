@@ -0,0 +1,81 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"context"
+	"net/http"
+	"time"
+)
+
+type componentHealth struct {
+	Status  string `json:"status"`
+	Details any    `json:"details,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (app *application) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	err := writeJSON(w, r, http.StatusOK, envelope{"status": "alive"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	components := map[string]componentHealth{}
+	ready := true
+
+	start := time.Now()
+	err := app.models.Tables.Ping(ctx)
+	if err != nil {
+		ready = false
+		components["database"] = componentHealth{Status: "down", Error: err.Error()}
+	} else {
+		components["database"] = componentHealth{
+			Status:  "up",
+			Details: envelope{"latencyMs": time.Since(start).Milliseconds()},
+		}
+	}
+
+	version, err := app.models.Tables.CurrentSchemaVersion(ctx)
+	switch {
+	case err != nil:
+		ready = false
+		components["schema"] = componentHealth{Status: "down", Error: err.Error()}
+	case version != data.SchemaVersion():
+		ready = false
+		components["schema"] = componentHealth{
+			Status:  "down",
+			Details: envelope{"version": version, "expected": data.SchemaVersion()},
+		}
+	default:
+		components["schema"] = componentHealth{
+			Status:  "up",
+			Details: envelope{"version": version, "expected": data.SchemaVersion()},
+		}
+	}
+
+	workers := app.workers.snapshot()
+	workersHealth := componentHealth{Status: "up", Details: workers}
+	for _, s := range workers {
+		if s.Status != "running" {
+			ready = false
+			workersHealth.Status = "down"
+		}
+	}
+	components["workers"] = workersHealth
+
+	status := http.StatusOK
+	body := envelope{"status": "ready", "components": components}
+	if !ready {
+		status = http.StatusServiceUnavailable
+		body["status"] = "unavailable"
+	}
+
+	err = writeJSON(w, r, status, body, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"avitotask/internal/validator"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -78,7 +81,9 @@ func writeJSON(w http.ResponseWriter, r *http.Request, status int, data any, hea
 		w.Header()[k] = v
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
 
 	w.WriteHeader(status)
 
@@ -88,3 +93,56 @@ func writeJSON(w http.ResponseWriter, r *http.Request, status int, data any, hea
 
 	return nil
 }
+
+func readQueryValue(qs url.Values, key string, v *validator.Validator) string {
+	values := qs[key]
+	if len(values) > 1 {
+		v.AddMessage(key, "validation.singleValue")
+		return ""
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func readIntQuery(qs url.Values, key string, defaultValue, min int, v *validator.Validator) int {
+	if _, found := qs[key]; !found {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(readQueryValue(qs, key, v))
+	if err != nil {
+		v.AddMessage(key, "validation.integer")
+		return defaultValue
+	}
+	if i < min {
+		v.AddMessage(key, "validation.min", min)
+		return defaultValue
+	}
+	return i
+}
+
+func (app *application) readPagination(qs url.Values, v *validator.Validator) (int32, int32) {
+	limit := readIntQuery(qs, "limit", app.config.Pagination.DefaultLimit, 1, v)
+	v.CheckMessage(limit <= app.config.Pagination.MaxLimit, "limit", "validation.max", app.config.Pagination.MaxLimit)
+
+	offset := readIntQuery(qs, "offset", 0, 0, v)
+
+	return int32(limit), int32(offset)
+}
+
+func readUsernameQuery(qs url.Values, v *validator.Validator) string {
+	username := readQueryValue(qs, "username", v)
+	v.Field("username", username, validator.Required())
+	return username
+}
+
+func readVersionParam(vars map[string]string, v *validator.Validator) int {
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil || version < 1 {
+		v.AddMessage("version", "validation.version")
+		return 0
+	}
+	return version
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	idempotencyReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKey    = 255
+)
+
+var (
+	ErrIdempotencyKeyInvalid    = errors.New("Idempotency-Key must be 1 to 255 visible ASCII characters")
+	ErrIdempotencyKeyReused     = errors.New("Idempotency-Key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this Idempotency-Key is still being processed")
+)
+
+// bodyRecorder passes a response through and keeps a copy of it.
+type bodyRecorder struct {
+	http.ResponseWriter
+	status       int
+	wroteHeaders bool
+	body         bytes.Buffer
+}
+
+func (rec *bodyRecorder) WriteHeader(status int) {
+	if !rec.wroteHeaders {
+		rec.status = status
+		rec.wroteHeaders = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *bodyRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeaders {
+		rec.status = http.StatusOK
+		rec.wroteHeaders = true
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *bodyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKey {
+		return false
+	}
+	for _, c := range key {
+		if c > unicode.MaxASCII || !unicode.IsPrint(c) || c == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint identifies what a request asks for: the same key sent
+// with another path, query or body is a different request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyScope identifies the caller a key belongs to. Creating a tender
+// or a bid names the caller in the body, every other POST in the username
+// parameter; requests that name nobody fall back to the client IP.
+func (app *application) idempotencyScope(r *http.Request, body []byte) string {
+	switch r.URL.Path {
+	case "/api/tenders/new":
+		var input struct {
+			CreatorUsername string `json:"creatorUsername"`
+		}
+		if json.Unmarshal(body, &input) == nil && input.CreatorUsername != "" {
+			return "user:" + input.CreatorUsername
+		}
+	case "/api/bids/new":
+		var input struct {
+			AuthorId string `json:"authorId"`
+		}
+		if json.Unmarshal(body, &input) == nil && input.AuthorId != "" {
+			return "author:" + input.AuthorId
+		}
+	default:
+		if username := r.URL.Query().Get("username"); username != "" {
+			return "user:" + username
+		}
+	}
+	return "ip:" + app.clientIP(r)
+}
+
+// idempotency makes POST requests with an Idempotency-Key header safe to
+// retry. The first request with a key runs and its response is stored; a
+// repeat within the TTL gets the stored response without running the
+// handler again. Server errors, rate limiting and conflicts depend on the
+// moment rather than on the request, so they are not stored and a retry
+// after one runs the request anew.
+func (app *application) idempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			app.badRequestResponse(w, r, ErrIdempotencyKeyInvalid)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+		scope := app.idempotencyScope(r, body)
+
+		claimed, err := app.models.Idempotency.Claim(scope, key, fingerprint, app.config.Idempotency.TTL.Std())
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !claimed {
+			app.replayResponse(w, r, scope, key, fingerprint)
+			return
+		}
+
+		rec := &bodyRecorder{ResponseWriter: w, status: http.StatusOK}
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := app.models.Idempotency.Release(scope, key); err != nil {
+				app.logError(r, slog.LevelError, err)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests || rec.status == http.StatusConflict {
+			return
+		}
+		err = app.models.Idempotency.Complete(scope, key, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes())
+		if err != nil {
+			app.logError(r, slog.LevelError, err)
+			return
+		}
+		stored = true
+	})
+}
+
+func (app *application) replayResponse(w http.ResponseWriter, r *http.Request, scope, key, fingerprint string) {
+	saved, err := app.models.Idempotency.Get(scope, key)
+	if err != nil {
+		if errors.Is(err, data.ErrIdempotencyKeyNotFound) {
+			// The first request failed and gave the key up in between.
+			app.conflictResponse(w, r, ErrIdempotencyKeyInProgress)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if saved.Fingerprint != fingerprint {
+		app.unprocessableResponse(w, r, ErrIdempotencyKeyReused)
+		return
+	}
+	if saved.Status == 0 {
+		app.conflictResponse(w, r, ErrIdempotencyKeyInProgress)
+		return
+	}
+
+	if saved.ContentType != "" {
+		w.Header().Set("Content-Type", saved.ContentType)
+	}
+	w.Header().Set(idempotencyReplayed, "true")
+	w.WriteHeader(saved.Status)
+	w.Write(saved.Body)
+}
+
+func (app *application) startIdempotencyCleanup() {
+	app.background("idempotency-cleanup", func(ctx context.Context) {
+		ticker := time.NewTicker(app.config.Idempotency.CleanupEvery.Std())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := app.models.Idempotency.DeleteExpired(); err != nil {
+					app.logger.Error("failed to delete expired idempotency keys", slog.String("error", err.Error()))
+				}
+			}
+		}
+	})
+}
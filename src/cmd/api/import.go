@@ -0,0 +1,372 @@
+package main
+
+import (
+	"avitotask/internal/config"
+	"avitotask/internal/data"
+	"avitotask/internal/i18n"
+	"avitotask/internal/validator"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	importCSV    = "csv"
+	importNDJSON = "ndjson"
+
+	maxImportRows  = 10000
+	maxImportBytes = 32 << 20
+)
+
+// tenderImportItem is one row of an import: a new tender and the id the
+// caller's own system knows it by.
+type tenderImportItem struct {
+	ExternalId string `json:"externalId"`
+	tenderInput
+}
+
+// tenderImportItemRules are the rules of tenderInput, except that the
+// organization and creator default to the importing user.
+func tenderImportItemRules() validator.FieldRules {
+	rules := tenderInputRules()
+	rules["organizationId"] = []validator.Rule{validator.UUID()}
+	delete(rules, "creatorUsername")
+	rules["externalId"] = []validator.Rule{validator.Required(), validator.MaxRunes(100)}
+	return rules
+}
+
+func (item tenderImportItem) validate(v *validator.Validator) {
+	v.Struct(item, tenderImportItemRules())
+}
+
+// set assigns a CSV cell to the field with the same JSON name.
+func (item *tenderImportItem) set(column, value string) {
+	switch column {
+	case "externalId":
+		item.ExternalId = value
+	case "name":
+		item.Name = value
+	case "description":
+		item.Description = value
+	case "serviceType":
+		item.ServiceType = value
+	case "organizationId":
+		item.OrganizationID = value
+	case "creatorUsername":
+		item.CreatorUsername = value
+	case "deadline":
+		item.Deadline = value
+	case "visibility":
+		item.Visibility = value
+	}
+}
+
+var importColumns = []string{"externalId", "name", "description", "serviceType", "organizationId", "creatorUsername", "deadline", "visibility"}
+
+type importResult struct {
+	ExternalId string `json:"externalId"`
+	TenderId   string `json:"tenderId"`
+	Created    bool   `json:"created"`
+}
+
+// decodeImportCSV reads rows under a header line that names the columns.
+// Columns may come in any order and may be left out.
+func decodeImportCSV(r io.Reader) ([]tenderImportItem, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrBulkEmpty
+		}
+		return nil, err
+	}
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if !slices.Contains(importColumns, column) {
+			return nil, fmt.Errorf("unknown column %q, known columns are %s", column, strings.Join(importColumns, ", "))
+		}
+		header[i] = column
+	}
+
+	var items []tenderImportItem
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == maxImportRows {
+			return nil, tooManyItems(maxImportRows)
+		}
+		var item tenderImportItem
+		for i, value := range record {
+			item.set(header[i], value)
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, ErrBulkEmpty
+	}
+	return items, nil
+}
+
+func decodeImport(r io.Reader, format string) ([]tenderImportItem, error) {
+	if format == importCSV {
+		return decodeImportCSV(r)
+	}
+	return decodeItems[tenderImportItem](r, maxImportRows)
+}
+
+// readImportFormat takes the format parameter, or failing that the content
+// type of the body.
+func readImportFormat(r *http.Request, v *validator.Validator) string {
+	format := readQueryValue(r.URL.Query(), "format", v)
+	if format != "" {
+		v.Field("format", format, validator.OneOf(importCSV, importNDJSON))
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		return importCSV
+	}
+	return importNDJSON
+}
+
+// importTenders creates a tender for each item whose external id the
+// organization has not imported yet. Items already imported are reported
+// with their existing tender and left alone, so a failed import can be
+// sent again as a whole.
+func (app *application) importTenders(r *http.Request, username, organizationId, mode string, dryRun bool, items []tenderImportItem) (*bulkResponse, error) {
+	seen := make(map[string]int, len(items))
+
+	return app.runBulk(r, mode, dryRun, len(items), func(tx *data.Tx, i int) (any, *actionError) {
+		item := items[i]
+		if item.CreatorUsername == "" {
+			item.CreatorUsername = username
+		}
+		if item.OrganizationID == "" {
+			item.OrganizationID = organizationId
+		}
+
+		v := validator.New()
+		item.validate(v)
+		if first, ok := seen[item.ExternalId]; ok && item.ExternalId != "" {
+			v.AddMessage("externalId", "validation.duplicate", item.ExternalId, first)
+		} else {
+			seen[item.ExternalId] = i
+		}
+		if err := app.checkServiceType(v, "serviceType", item.ServiceType); err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		if !v.Valid() {
+			return nil, actionInvalid(v)
+		}
+
+		if item.CreatorUsername != username || item.OrganizationID != organizationId {
+			return nil, actionFailed(http.StatusForbidden, data.ErrNoRights)
+		}
+
+		tenderId, err := tx.GetImportedTender(organizationId, item.ExternalId)
+		if err == nil {
+			return importResult{ExternalId: item.ExternalId, TenderId: tenderId}, nil
+		}
+		if !errors.Is(err, data.ErrImportNotFound) {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+
+		tender := item.tender()
+		if err := tx.InsertTender(&tender); err != nil {
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		if err := tx.InsertTenderImport(organizationId, item.ExternalId, tender.Id); err != nil {
+			if errors.Is(err, data.ErrImportExists) {
+				return nil, actionFailed(http.StatusConflict, err)
+			}
+			return nil, actionFailed(http.StatusInternalServerError, err)
+		}
+		return importResult{ExternalId: item.ExternalId, TenderId: tender.Id, Created: true}, nil
+	})
+}
+
+// importTendersHandler imports tenders of the user's organization from CSV
+// or NDJSON. With dryRun=true every row is checked, including against the
+// database, but nothing is saved.
+func (app *application) importTendersHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+	mode := readBulkMode(q, v)
+	format := readImportFormat(r, v)
+	dryRun := readQueryValue(q, "dryRun", v)
+	v.Field("dryRun", dryRun, validator.OneOf("true", "false"))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	items, err := decodeImport(http.MaxBytesReader(w, r.Body, maxImportBytes), format)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	res, err := app.importTenders(r, username, organizationId, mode, dryRun == "true", items)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeBulkResponse(w, r, res)
+}
+
+// importCommand runs "api import": the import endpoint for a file, straight
+// against the database. The report is printed to stdout; the exit status is
+// 1 if any row failed.
+func importCommand(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("file", "", "CSV or NDJSON file to import, - for stdin")
+	format := fs.String("format", "", "csv or ndjson, by default taken from the file extension")
+	username := fs.String("username", "", "user the tenders are created by")
+	mode := fs.String("mode", bulkPartial, "atomic or partial")
+	dryRun := fs.Bool("dry-run", false, "check every row without saving anything")
+	configFile := fs.String("config", "", "path to a JSON or YAML config file")
+	dsn := fs.String("db-dsn", "", "PostgreSQL DSN")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *format == "" {
+		*format = importNDJSON
+		if strings.EqualFold(filepath.Ext(*file), ".csv") {
+			*format = importCSV
+		}
+	}
+	v := validator.New()
+	v.Field("file", *file, validator.Required())
+	v.Field("username", *username, validator.Required())
+	v.Field("format", *format, validator.OneOf(importCSV, importNDJSON))
+	v.Field("mode", *mode, validator.OneOf(bulkAtomic, bulkPartial))
+	if !v.Valid() {
+		errs := v.Localized(i18n.Default)
+		fields := make([]string, 0, len(errs))
+		for field := range errs {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		for _, field := range fields {
+			fmt.Fprintf(stderr, "-%s: %s\n", field, errs[field])
+		}
+		return 2
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = append(configArgs, "-config", *configFile)
+	}
+	if *dsn != "" {
+		configArgs = append(configArgs, "-db-dsn", *dsn)
+	}
+	cfg, _, err := config.Load(configArgs, getenv)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	items, err := decodeImport(in, *format)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	app := &application{
+		config: cfg,
+		logger: slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: level})),
+		models: data.NewModels(db),
+	}
+	if err := checkSchema(app.models); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	userId, err := app.models.Tenders.GetUserID(*username)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	// Results are rendered by the same code as for the endpoint, which
+	// takes the language and log attributes from a request.
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/api/tenders/import", nil)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	res, err := app.importTenders(r, *username, organizationId, *mode, *dryRun, items)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(res); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if res.Failed > 0 {
+		return 1
+	}
+	return 0
+}
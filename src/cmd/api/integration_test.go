@@ -0,0 +1,1017 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/pgtest"
+	"avitotask/internal/seed"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func TestMain(m *testing.M) {
+	pgtest.Main(m)
+}
+
+// apiServer serves the application on a fresh database holding the
+// organizations and employees of a small seed, and records which routes the
+// requests reached.
+type apiServer struct {
+	t      *testing.T
+	app    *application
+	url    string
+	seeded *seed.Result
+
+	mu     sync.Mutex
+	routes map[string]bool
+}
+
+func newAPIServer(t *testing.T) *apiServer {
+	t.Helper()
+
+	app := newTestApplication(t)
+	app.models = data.NewModels(pgtest.Open(t))
+	app.config.RateLimit.Enabled = false
+
+	if err := seed.Prepare(app.models); err != nil {
+		t.Fatal(err)
+	}
+	opts := seed.DefaultOptions()
+	opts.Tenders = 0
+	seeded, err := seed.Run(app.models, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.config.Approval.Quorum = opts.Quorum
+	app.config.Admin.Usernames = []string{seeded.Employees[len(seeded.Employees)-1].Username}
+
+	s := &apiServer{t: t, app: app, seeded: seeded, routes: make(map[string]bool)}
+
+	router := app.router()
+	router.Use(s.recordRoute)
+	ts := httptest.NewServer(app.requestID(app.recoverPanic(router)))
+	t.Cleanup(ts.Close)
+	s.url = ts.URL
+
+	return s
+}
+
+func (s *apiServer) recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if template, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+			s.mu.Lock()
+			s.routes[r.Method+" "+template] = true
+			s.mu.Unlock()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// employee returns the username of the i-th employee of the o-th seeded
+// organization.
+func (s *apiServer) employee(o, i int) string {
+	return s.seeded.Employees[o*len(s.seeded.Members[s.organization(o)])+i].Username
+}
+
+func (s *apiServer) organization(o int) string {
+	return s.seeded.Organizations[o].Id
+}
+
+func (s *apiServer) userId(username string) string {
+	s.t.Helper()
+
+	userId, err := s.app.models.Tenders.GetUserID(username)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return userId
+}
+
+// organizationWith creates an organization with the given responsibles, who
+// are created as well.
+func (s *apiServer) organizationWith(name string, usernames ...string) string {
+	s.t.Helper()
+
+	organization := &data.Organization{Id: uuid.New().String(), Name: name, Type: "LLC"}
+	if err := s.app.models.Tenders.InsertOrganization(organization); err != nil {
+		s.t.Fatal(err)
+	}
+	for _, username := range usernames {
+		employeeId := s.employeeWithout(username)
+		if err := s.app.models.Tenders.AddResponsible(organization.Id, employeeId); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	return organization.Id
+}
+
+// employeeWithout creates an employee who is not responsible for any
+// organization.
+func (s *apiServer) employeeWithout(username string) string {
+	s.t.Helper()
+
+	employee := &data.Employee{Id: uuid.New().String(), Username: username, FirstName: "Test", LastName: "User"}
+	if err := s.app.models.Tenders.InsertEmployee(employee); err != nil {
+		s.t.Fatal(err)
+	}
+	return employee.Id
+}
+
+type apiResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// do sends a request. A string body is sent as is, anything else as JSON.
+func (s *apiServer) do(t *testing.T, method, path string, query url.Values, body any) *apiResponse {
+	t.Helper()
+	return s.doWithHeader(t, method, path, query, body, nil)
+}
+
+func (s *apiServer) doWithHeader(t *testing.T, method, path string, query url.Values, body any, header http.Header) *apiResponse {
+	t.Helper()
+
+	var reader io.Reader
+	switch body := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(js)
+	}
+
+	target := s.url + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &apiResponse{status: res.StatusCode, header: res.Header, body: resBody}
+}
+
+// expect checks the status and, for error responses, the error code.
+func (res *apiResponse) expect(t *testing.T, status int, code string) *apiResponse {
+	t.Helper()
+
+	if res.status != status {
+		t.Fatalf("got status %d, want %d: %s", res.status, status, res.body)
+	}
+	if code != "" {
+		var p problem
+		if err := json.Unmarshal(res.body, &p); err != nil {
+			t.Fatalf("decode error response: %v: %s", err, res.body)
+		}
+		if p.Code != code {
+			t.Fatalf("got code %s, want %s: %s", p.Code, code, res.body)
+		}
+	}
+	return res
+}
+
+func (res *apiResponse) decode(t *testing.T, v any) {
+	t.Helper()
+
+	if err := json.Unmarshal(res.body, v); err != nil {
+		t.Fatalf("decode response: %v: %s", err, res.body)
+	}
+}
+
+// user builds the query of a request made by username, with optional
+// further key and value pairs.
+func user(username string, pairs ...string) url.Values {
+	q := url.Values{}
+	if username != "" {
+		q.Set("username", username)
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		q.Add(pairs[i], pairs[i+1])
+	}
+	return q
+}
+
+type tenderResponse struct {
+	Id          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	ServiceType string               `json:"serviceType"`
+	Status      string               `json:"status"`
+	Visibility  string               `json:"visibility"`
+	Version     int                  `json:"version"`
+	Stats       *data.TenderBidStats `json:"stats"`
+}
+
+type bidResponse struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	AuthorType  string `json:"authorType"`
+	AuthorId    string `json:"authorId"`
+	Version     int    `json:"version"`
+	Approvals   int    `json:"approvals"`
+}
+
+func (s *apiServer) createTender(t *testing.T, username, organizationId, serviceType string) tenderResponse {
+	t.Helper()
+
+	input := tenderInput{
+		Name:            "Office renovation",
+		Description:     "Two floors, open space",
+		ServiceType:     serviceType,
+		OrganizationID:  organizationId,
+		CreatorUsername: username,
+	}
+	var tender tenderResponse
+	s.do(t, "POST", "/api/tenders/new", nil, input).expect(t, http.StatusOK, "").decode(t, &tender)
+	return tender
+}
+
+func (s *apiServer) publishTender(t *testing.T, username, tenderId string) {
+	t.Helper()
+
+	path := fmt.Sprintf("/api/tenders/%s/status", tenderId)
+	s.do(t, "PUT", path, user(username, "status", "Published"), nil).expect(t, http.StatusOK, "")
+}
+
+func (s *apiServer) tenderStatus(t *testing.T, username, tenderId string) string {
+	t.Helper()
+
+	path := fmt.Sprintf("/api/tenders/%s/status", tenderId)
+	return string(s.do(t, "GET", path, user(username), nil).expect(t, http.StatusOK, "").body)
+}
+
+func (s *apiServer) createBid(t *testing.T, tenderId, authorType, authorId string) bidResponse {
+	t.Helper()
+
+	input := BidInput{
+		Name:        "Fixed price offer",
+		Description: "Done in two weeks",
+		TenderId:    tenderId,
+		AuthorType:  authorType,
+		AuthorId:    authorId,
+	}
+	var bid bidResponse
+	s.do(t, "POST", "/api/bids/new", nil, input).expect(t, http.StatusOK, "").decode(t, &bid)
+	return bid
+}
+
+func (s *apiServer) publishBid(t *testing.T, username, bidId string) {
+	t.Helper()
+
+	path := fmt.Sprintf("/api/bids/%s/status", bidId)
+	s.do(t, "PUT", path, user(username, "status", "Published"), nil).expect(t, http.StatusOK, "")
+}
+
+func (s *apiServer) decide(t *testing.T, username, bidId, decision string) *apiResponse {
+	t.Helper()
+
+	path := fmt.Sprintf("/api/bids/%s/submit_decision", bidId)
+	return s.do(t, "PUT", path, user(username, "decision", decision), nil)
+}
+
+// TestAPI runs the API against PostgreSQL. The subtests share one database
+// and build on each other, so they run in order.
+func TestAPI(t *testing.T) {
+	s := newAPIServer(t)
+
+	// Organization 0 owns the tenders, 1 bids on them and 2 is an outsider
+	// whose last employee is the admin.
+	owner, owner2, owner3 := s.employee(0, 0), s.employee(0, 1), s.employee(0, 2)
+	bidder, bidder2 := s.employee(1, 0), s.employee(1, 1)
+	outsider := s.employee(2, 0)
+	admin := s.app.config.Admin.Usernames[0]
+	loner := "loner"
+	s.employeeWithout(loner)
+
+	var tender tenderResponse
+	var bid bidResponse
+
+	t.Run("health", func(t *testing.T) {
+		s.do(t, "GET", "/api/ping", nil, nil).expect(t, http.StatusOK, "")
+		s.do(t, "GET", "/healthz", nil, nil).expect(t, http.StatusOK, "")
+		s.do(t, "GET", "/api/statuses", nil, nil).expect(t, http.StatusOK, "")
+		s.do(t, "GET", "/api/docs", nil, nil).expect(t, http.StatusOK, "")
+		s.do(t, "GET", "/api/openapi.json", nil, nil).expect(t, http.StatusOK, "")
+
+		var ready struct {
+			Status string `json:"status"`
+		}
+		s.do(t, "GET", "/readyz", nil, nil).expect(t, http.StatusOK, "").decode(t, &ready)
+		if ready.Status != "ready" {
+			t.Errorf("got readiness %q", ready.Status)
+		}
+	})
+
+	t.Run("service types", func(t *testing.T) {
+		var serviceTypes []data.ServiceType
+		s.do(t, "GET", "/api/service-types", nil, nil).expect(t, http.StatusOK, "").decode(t, &serviceTypes)
+		if len(serviceTypes) == 0 {
+			t.Fatal("service type catalogue is empty")
+		}
+
+		input := serviceTypeInput{Code: "Cleaning", Names: map[string]string{"en": "Cleaning"}}
+		s.do(t, "POST", "/api/service-types/new", user("nobody"), input).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		s.do(t, "POST", "/api/service-types/new", user(owner), input).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "POST", "/api/service-types/new", user(admin), input).expect(t, http.StatusOK, "")
+		s.do(t, "POST", "/api/service-types/new", user(admin), input).expect(t, http.StatusConflict, CodeServiceTypeExists)
+
+		edit := serviceTypeEditInput{Names: map[string]string{"en": "Cleaning services"}}
+		s.do(t, "PATCH", "/api/service-types/Cleaning", user(owner), edit).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PATCH", "/api/service-types/Cleaning", user(admin), edit).expect(t, http.StatusOK, "")
+
+		s.do(t, "DELETE", "/api/service-types/Cleaning", user(owner), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "DELETE", "/api/service-types/Cleaning", user(admin), nil).expect(t, http.StatusNoContent, "")
+	})
+
+	t.Run("tenders", func(t *testing.T) {
+		input := tenderInput{
+			Name:            "Office renovation",
+			Description:     "Two floors, open space",
+			ServiceType:     "Construction",
+			OrganizationID:  s.organization(0),
+			CreatorUsername: owner,
+		}
+
+		invalid := input
+		invalid.Name = ""
+		s.do(t, "POST", "/api/tenders/new", nil, invalid).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		unknown := input
+		unknown.CreatorUsername = "nobody"
+		s.do(t, "POST", "/api/tenders/new", nil, unknown).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		lonely := input
+		lonely.CreatorUsername = loner
+		s.do(t, "POST", "/api/tenders/new", nil, lonely).expect(t, http.StatusForbidden, CodeNoRights)
+		foreign := input
+		foreign.OrganizationID = s.organization(1)
+		s.do(t, "POST", "/api/tenders/new", nil, foreign).expect(t, http.StatusForbidden, CodeNoRights)
+
+		tender = s.createTender(t, owner, s.organization(0), "Construction")
+		if tender.Status != "Created" || tender.Version != 1 {
+			t.Fatalf("new tender has status %s and version %d", tender.Status, tender.Version)
+		}
+
+		s.do(t, "GET", "/api/tenders", user("", "limit", "0"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "GET", "/api/tenders", user("", "service_type", "Astrology"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "GET", "/api/tenders", nil, nil).expect(t, http.StatusOK, "")
+
+		var mine []tenderResponse
+		s.do(t, "GET", "/api/tenders/my", user(owner2), nil).expect(t, http.StatusOK, "").decode(t, &mine)
+		if len(mine) != 1 || mine[0].Id != tender.Id {
+			t.Errorf("got %d tenders of the organization, want only %s", len(mine), tender.Id)
+		}
+		s.do(t, "GET", "/api/tenders/my", user("nobody"), nil).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		s.do(t, "GET", "/api/tenders/my", user(loner), nil).expect(t, http.StatusForbidden, CodeOrganizationNotFound)
+
+		path := "/api/tenders/" + tender.Id
+		s.do(t, "GET", "/api/tenders/not-a-uuid", nil, nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		s.do(t, "GET", path, user(outsider), nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		var detail tenderResponse
+		s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Stats == nil {
+			t.Error("owner got no bid statistics")
+		}
+
+		s.do(t, "GET", path+"/status", nil, nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "GET", path+"/status", user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "GET", "/api/tenders/"+uuid.NewString()+"/status", user(owner), nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		if status := s.tenderStatus(t, owner, tender.Id); status != "Created" {
+			t.Errorf("got status %s, want Created", status)
+		}
+
+		s.do(t, "PUT", path+"/status", user(owner, "status", "Archived"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/status", user(outsider, "status", "Published"), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.publishTender(t, owner, tender.Id)
+
+		s.do(t, "GET", path, user(outsider), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Stats != nil {
+			t.Error("outsider got bid statistics")
+		}
+	})
+
+	t.Run("tender versions", func(t *testing.T) {
+		path := "/api/tenders/" + tender.Id
+
+		edit := tenderEditInput{Name: "Office and lobby renovation"}
+		s.do(t, "PATCH", path+"/edit", user(outsider), edit).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PATCH", path+"/edit", user(owner), tenderEditInput{ServiceType: "Astrology"}).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+		var edited tenderResponse
+		s.do(t, "PATCH", path+"/edit", user(owner), edit).expect(t, http.StatusOK, "").decode(t, &edited)
+		if edited.Version != 2 || edited.Name != edit.Name || edited.Description != tender.Description {
+			t.Fatalf("after edit got version %d, name %q, description %q", edited.Version, edited.Name, edited.Description)
+		}
+
+		s.do(t, "PUT", path+"/rollback/first", user(owner), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/rollback/0", user(owner), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/rollback/42", user(owner), nil).expect(t, http.StatusNotFound, CodeTenderVersionNotFound)
+		s.do(t, "PUT", path+"/rollback/1", user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+
+		var rolledBack tenderResponse
+		s.do(t, "PUT", path+"/rollback/1", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &rolledBack)
+		if rolledBack.Version != 3 || rolledBack.Name != tender.Name {
+			t.Fatalf("after rollback got version %d, name %q", rolledBack.Version, rolledBack.Name)
+		}
+		// Rolling back does not change the status.
+		if rolledBack.Status != "Published" {
+			t.Errorf("rollback changed the status to %s", rolledBack.Status)
+		}
+
+		s.do(t, "POST", path+"/clone", user(owner, "version", "42"), nil).expect(t, http.StatusNotFound, CodeTenderVersionNotFound)
+		s.do(t, "POST", path+"/clone", user(outsider), nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		var clone tenderResponse
+		s.do(t, "POST", path+"/clone", user(owner, "version", "2"), nil).expect(t, http.StatusOK, "").decode(t, &clone)
+		if clone.Id == tender.Id || clone.Status != "Created" || clone.Version != 1 || clone.Name != edit.Name {
+			t.Errorf("unexpected clone %+v", clone)
+		}
+	})
+
+	t.Run("bids", func(t *testing.T) {
+		draft := s.createTender(t, owner, s.organization(0), "Construction")
+
+		input := BidInput{Name: "Offer", Description: "Fixed price", TenderId: tender.Id, AuthorType: "User", AuthorId: s.userId(bidder)}
+
+		invalid := input
+		invalid.AuthorType = "Robot"
+		s.do(t, "POST", "/api/bids/new", nil, invalid).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		missing := input
+		missing.TenderId = uuid.NewString()
+		s.do(t, "POST", "/api/bids/new", nil, missing).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		unpublished := input
+		unpublished.TenderId = draft.Id
+		s.do(t, "POST", "/api/bids/new", nil, unpublished).expect(t, http.StatusForbidden, CodeTenderNotPublished)
+		selfUser := input
+		selfUser.AuthorId = s.userId(owner2)
+		s.do(t, "POST", "/api/bids/new", nil, selfUser).expect(t, http.StatusForbidden, CodeSelfBidForbidden)
+		selfOrganization := input
+		selfOrganization.AuthorType = "Organization"
+		selfOrganization.AuthorId = s.organization(0)
+		s.do(t, "POST", "/api/bids/new", nil, selfOrganization).expect(t, http.StatusForbidden, CodeSelfBidForbidden)
+
+		bid = s.createBid(t, tender.Id, "User", s.userId(bidder))
+		if bid.Status != "Created" || bid.Version != 1 {
+			t.Fatalf("new bid has status %s and version %d", bid.Status, bid.Version)
+		}
+
+		var mine []bidResponse
+		s.do(t, "GET", "/api/bids/my", user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &mine)
+		if len(mine) != 1 || mine[0].Id != bid.Id {
+			t.Errorf("got %d bids of the user, want only %s", len(mine), bid.Id)
+		}
+		s.do(t, "GET", "/api/bids/my", user("nobody"), nil).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+
+		path := "/api/bids/" + bid.Id
+		s.do(t, "GET", path+"/status", user(outsider), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+		// Responsibles of the bidder's organization act for its users.
+		status := s.do(t, "GET", path+"/status", user(bidder2), nil).expect(t, http.StatusOK, "").body
+		if string(status) != "Created" {
+			t.Errorf("got status %s, want Created", status)
+		}
+
+		// The tender owner does not see bids before they are published.
+		s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+
+		s.do(t, "PUT", path+"/status", user(bidder, "status", "Withdrawn"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/status", user(outsider, "status", "Published"), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+		s.do(t, "PUT", path+"/status", user(owner, "status", "Published"), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+		s.do(t, "PUT", "/api/bids/"+uuid.NewString()+"/status", user(bidder, "status", "Published"), nil).expect(t, http.StatusNotFound, CodeBidNotFound)
+		s.publishBid(t, bidder2, bid.Id)
+
+		var detail bidResponse
+		s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Description != "" {
+			t.Error("tender owner sees the bid description")
+		}
+		s.do(t, "GET", path, user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Description == "" {
+			t.Error("bidder does not see the bid description")
+		}
+
+		list := fmt.Sprintf("/api/bids/%s/list", tender.Id)
+		s.do(t, "GET", fmt.Sprintf("/api/bids/%s/list", uuid.NewString()), user(owner), nil).expect(t, http.StatusUnauthorized, CodeTenderNotFound)
+		s.do(t, "GET", list, user(outsider), nil).expect(t, http.StatusForbidden, CodeNotTenderResponsible)
+		s.do(t, "GET", list, user(loner), nil).expect(t, http.StatusForbidden, CodeOrganizationNotFound)
+		s.do(t, "GET", fmt.Sprintf("/api/bids/%s/list", draft.Id), user(owner), nil).expect(t, http.StatusNotFound, CodeBidOrTenderNotFound)
+		var bids []bidResponse
+		s.do(t, "GET", list, user(owner), nil).expect(t, http.StatusOK, "").decode(t, &bids)
+		if len(bids) != 1 || bids[0].Id != bid.Id {
+			t.Errorf("got %d bids for the tender, want only %s", len(bids), bid.Id)
+		}
+	})
+
+	t.Run("bid versions", func(t *testing.T) {
+		path := "/api/bids/" + bid.Id
+
+		edit := bidEditInput{Description: "Done in ten days"}
+		s.do(t, "PATCH", path+"/edit", user(outsider), edit).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+		s.do(t, "PATCH", path+"/edit", user(bidder), bidEditInput{Name: strings.Repeat("x", 101)}).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+		var edited bidResponse
+		s.do(t, "PATCH", path+"/edit", user(bidder), edit).expect(t, http.StatusOK, "").decode(t, &edited)
+		if edited.Version != 2 || edited.Status != "Published" {
+			t.Fatalf("after edit got version %d and status %s", edited.Version, edited.Status)
+		}
+
+		s.do(t, "PUT", path+"/rollback/0", user(bidder), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/rollback/42", user(bidder), nil).expect(t, http.StatusNotFound, CodeBidVersionNotFound)
+		s.do(t, "PUT", path+"/rollback/1", user(owner), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+
+		var rolledBack bidResponse
+		s.do(t, "PUT", path+"/rollback/1", user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &rolledBack)
+		if rolledBack.Version != 3 {
+			t.Fatalf("after rollback got version %d, want 3", rolledBack.Version)
+		}
+		var detail bidResponse
+		s.do(t, "GET", path, user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Description != "Done in two weeks" {
+			t.Errorf("rollback did not restore the description, got %q", detail.Description)
+		}
+	})
+
+	t.Run("withdraw and resubmit", func(t *testing.T) {
+		offer := s.createBid(t, tender.Id, "Organization", s.organization(1))
+		path := "/api/bids/" + offer.Id
+
+		s.do(t, "PUT", path+"/withdraw", user(outsider), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+		s.do(t, "PUT", path+"/withdraw", user(bidder, "override", "maybe"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/resubmit", user(bidder), nil).expect(t, http.StatusForbidden, CodeBidNotWithdrawn)
+
+		var withdrawn bidResponse
+		s.do(t, "PUT", path+"/withdraw", user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &withdrawn)
+		if withdrawn.Status != "Withdrawn" {
+			t.Fatalf("got status %s, want Withdrawn", withdrawn.Status)
+		}
+		s.do(t, "PUT", path+"/withdraw", user(bidder), nil).expect(t, http.StatusForbidden, CodeBidNotWithdrawable)
+		s.do(t, "PUT", path+"/status", user(bidder, "status", "Published"), nil).expect(t, http.StatusForbidden, CodeBidWithdrawn)
+		s.do(t, "PATCH", path+"/edit", user(bidder), bidEditInput{Name: "Withdrawn offer"}).expect(t, http.StatusConflict, CodeBidNotEditable)
+		s.do(t, "PUT", path+"/rollback/1", user(bidder), nil).expect(t, http.StatusConflict, CodeBidNotEditable)
+		s.do(t, "PUT", path+"/resubmit", user(outsider), nil).expect(t, http.StatusForbidden, CodeNotBidResponsible)
+
+		var resubmitted bidResponse
+		s.do(t, "PUT", path+"/resubmit", user(bidder2), nil).expect(t, http.StatusOK, "").decode(t, &resubmitted)
+		if resubmitted.Status != "Published" || resubmitted.Version != withdrawn.Version+1 {
+			t.Errorf("got status %s, version %d, want Published, version %d", resubmitted.Status, resubmitted.Version, withdrawn.Version+1)
+		}
+
+		s.do(t, "PUT", path+"/status", user(bidder, "status", "Canceled"), nil).expect(t, http.StatusOK, "")
+		s.do(t, "PUT", path+"/withdraw", user(bidder), nil).expect(t, http.StatusForbidden, CodeBidNotWithdrawable)
+		s.do(t, "PATCH", path+"/edit", user(bidder), bidEditInput{Name: "Canceled offer"}).expect(t, http.StatusConflict, CodeBidNotEditable)
+	})
+
+	t.Run("submit decision", func(t *testing.T) {
+		path := fmt.Sprintf("/api/bids/%s/submit_decision", bid.Id)
+
+		s.do(t, "PUT", "/api/bids/not-a-uuid/submit_decision", user(owner, "decision", "Approved"), nil).expect(t, http.StatusNotFound, CodeBidNotFound)
+		s.do(t, "PUT", path, user(owner), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path, user(owner, "decision", "Maybe"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path, user("", "decision", "Approved"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.decide(t, "nobody", bid.Id, "Approved").expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		s.decide(t, loner, bid.Id, "Approved").expect(t, http.StatusUnauthorized, CodeOrganizationNotFound)
+		s.decide(t, owner, uuid.NewString(), "Approved").expect(t, http.StatusNotFound, CodeBidNotFound)
+		s.decide(t, outsider, bid.Id, "Approved").expect(t, http.StatusForbidden, CodeNotTenderResponsible)
+		// The bidder's organization does not own the tender either.
+		s.decide(t, bidder, bid.Id, "Approved").expect(t, http.StatusForbidden, CodeNotTenderResponsible)
+
+		created := s.createBid(t, tender.Id, "User", s.userId(bidder2))
+		s.decide(t, owner, created.Id, "Approved").expect(t, http.StatusForbidden, CodeBidInactive)
+		s.decide(t, owner, created.Id, "Rejected").expect(t, http.StatusForbidden, CodeBidInactive)
+
+		s.decide(t, owner, bid.Id, "Approved").expect(t, http.StatusOK, "")
+		s.decide(t, owner, bid.Id, "Approved").expect(t, http.StatusForbidden, CodeBidAlreadyApproved)
+
+		var pending []data.PendingDecision
+		s.do(t, "GET", "/api/decisions/pending", user(owner2), nil).expect(t, http.StatusOK, "").decode(t, &pending)
+		if len(pending) != 1 || pending[0].Bid.Id != bid.Id {
+			t.Errorf("got %d pending decisions, want only %s", len(pending), bid.Id)
+		}
+		s.do(t, "GET", "/api/decisions/pending", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &pending)
+		if len(pending) != 0 {
+			t.Errorf("decided bid is still pending for its reviewer")
+		}
+
+		// Approvals only count for the version they were given to.
+		s.do(t, "PATCH", "/api/bids/"+bid.Id+"/edit", user(bidder), bidEditInput{Name: "Revised offer"}).expect(t, http.StatusOK, "")
+		s.decide(t, owner, bid.Id, "Approved").expect(t, http.StatusOK, "")
+
+		var versions []data.VersionApprovals
+		s.do(t, "GET", "/api/bids/"+bid.Id+"/approvals/versions", user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "GET", "/api/bids/"+bid.Id+"/approvals/versions", user(owner3), nil).expect(t, http.StatusOK, "").decode(t, &versions)
+		if len(versions) != 4 || !versions[0].Current || versions[0].Approvals != 1 || versions[1].Approvals != 1 {
+			t.Errorf("unexpected approvals by version %+v", versions)
+		}
+
+		var approvals []data.Approval
+		s.do(t, "GET", "/api/bids/"+bid.Id+"/approvals", user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "GET", "/api/bids/"+bid.Id+"/approvals", user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &approvals)
+		if len(approvals) != 2 || approvals[0].Username != owner || !approvals[0].Current || approvals[1].Current {
+			t.Errorf("unexpected approvals %+v", approvals)
+		}
+
+		s.do(t, "DELETE", "/api/bids/"+bid.Id+"/approvals/me", user(owner2), nil).expect(t, http.StatusNotFound, CodeApprovalNotFound)
+		s.do(t, "DELETE", "/api/bids/"+bid.Id+"/approvals/me", user(owner), nil).expect(t, http.StatusNoContent, "")
+		s.do(t, "DELETE", "/api/bids/"+bid.Id+"/approvals/me", user(owner), nil).expect(t, http.StatusNotFound, CodeApprovalNotFound)
+	})
+
+	t.Run("quorum closes the tender", func(t *testing.T) {
+		// The organization has three responsibles and the quorum is three.
+		s.decide(t, owner, bid.Id, "Approved").expect(t, http.StatusOK, "")
+		s.decide(t, owner2, bid.Id, "Approved").expect(t, http.StatusOK, "")
+		if status := s.tenderStatus(t, owner, tender.Id); status != "Published" {
+			t.Fatalf("tender is %s after two of three approvals", status)
+		}
+
+		var detail bidResponse
+		s.do(t, "GET", "/api/bids/"+bid.Id, user(owner3), nil).expect(t, http.StatusOK, "").decode(t, &detail)
+		if detail.Approvals != 2 {
+			t.Errorf("got %d approvals, want 2", detail.Approvals)
+		}
+
+		s.decide(t, owner3, bid.Id, "Approved").expect(t, http.StatusOK, "")
+		if status := s.tenderStatus(t, owner, tender.Id); status != "Closed" {
+			t.Fatalf("tender is %s after the quorum approved", status)
+		}
+
+		s.decide(t, owner3, bid.Id, "Rejected").expect(t, http.StatusForbidden, CodeTenderInactive)
+		s.do(t, "DELETE", "/api/bids/"+bid.Id+"/approvals/me", user(owner3), nil).expect(t, http.StatusForbidden, CodeTenderInactive)
+		s.do(t, "POST", "/api/bids/new", nil, BidInput{
+			Name: "Late offer", Description: "Too late", TenderId: tender.Id, AuthorType: "User", AuthorId: s.userId(bidder),
+		}).expect(t, http.StatusForbidden, CodeTenderNotPublished)
+	})
+
+	t.Run("small organization closes with fewer approvals", func(t *testing.T) {
+		solo := s.organizationWith("Solo Works", "solo.owner")
+		open := s.createTender(t, "solo.owner", solo, "Delivery")
+		s.publishTender(t, "solo.owner", open.Id)
+
+		offer := s.createBid(t, open.Id, "User", s.userId(bidder))
+		s.publishBid(t, bidder, offer.Id)
+
+		s.decide(t, "solo.owner", offer.Id, "Approved").expect(t, http.StatusOK, "")
+		if status := s.tenderStatus(t, "solo.owner", open.Id); status != "Closed" {
+			t.Errorf("tender is %s after its only responsible approved", status)
+		}
+	})
+
+	t.Run("rejection cancels the bid", func(t *testing.T) {
+		open := s.createTender(t, owner, s.organization(0), "Delivery")
+		s.publishTender(t, owner, open.Id)
+
+		offer := s.createBid(t, open.Id, "Organization", s.organization(1))
+		s.publishBid(t, bidder, offer.Id)
+		s.decide(t, owner, offer.Id, "Approved").expect(t, http.StatusOK, "")
+
+		var rejected bidResponse
+		s.decide(t, owner2, offer.Id, "Rejected").expect(t, http.StatusOK, "").decode(t, &rejected)
+		if rejected.Status != "Canceled" {
+			t.Fatalf("got status %s, want Canceled", rejected.Status)
+		}
+		s.decide(t, owner3, offer.Id, "Approved").expect(t, http.StatusForbidden, CodeBidInactive)
+		if status := s.tenderStatus(t, owner, open.Id); status != "Published" {
+			t.Errorf("rejection changed the tender status to %s", status)
+		}
+	})
+
+	t.Run("visibility and invitations", func(t *testing.T) {
+		open := s.createTender(t, owner, s.organization(0), "Manufacture")
+		s.publishTender(t, owner, open.Id)
+		path := "/api/tenders/" + open.Id
+
+		s.do(t, "PUT", path+"/visibility", user(owner, "visibility", "secret"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "PUT", path+"/visibility", user(outsider, "visibility", data.VisibilityPrivate), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PUT", path+"/visibility", user(owner, "visibility", data.VisibilityPrivate), nil).expect(t, http.StatusOK, "")
+		s.do(t, "GET", path, user(bidder), nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+
+		var clone tenderResponse
+		s.do(t, "POST", path+"/clone", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &clone)
+		if clone.Visibility != data.VisibilityPrivate {
+			t.Errorf("clone of a private tender got visibility %s", clone.Visibility)
+		}
+
+		invite := invitationInput{InviteeType: "Organization", InviteeId: s.organization(1)}
+		s.do(t, "POST", path+"/invitations", user(outsider), invite).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "POST", path+"/invitations", user(owner), invitationInput{InviteeType: "User", InviteeId: uuid.NewString()}).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		var invitation data.Invitation
+		s.do(t, "POST", path+"/invitations", user(owner), invite).expect(t, http.StatusOK, "").decode(t, &invitation)
+		s.do(t, "POST", path+"/invitations", user(owner), invite).expect(t, http.StatusConflict, CodeInvitationExists)
+
+		var other data.Invitation
+		s.do(t, "POST", path+"/invitations", user(owner), invitationInput{InviteeType: "User", InviteeId: s.userId(outsider)}).expect(t, http.StatusOK, "").decode(t, &other)
+
+		var invitations []data.Invitation
+		s.do(t, "GET", path+"/invitations", user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "GET", path+"/invitations", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &invitations)
+		if len(invitations) != 2 {
+			t.Errorf("got %d invitations, want 2", len(invitations))
+		}
+
+		s.do(t, "DELETE", path+"/invitations/"+other.Id, user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "DELETE", path+"/invitations/"+other.Id, user(owner), nil).expect(t, http.StatusNoContent, "")
+		s.do(t, "DELETE", path+"/invitations/"+other.Id, user(owner), nil).expect(t, http.StatusNotFound, CodeInvitationNotFound)
+
+		s.do(t, "GET", "/api/invitations/my", user(bidder), nil).expect(t, http.StatusOK, "").decode(t, &invitations)
+		if len(invitations) != 1 || invitations[0].Id != invitation.Id {
+			t.Errorf("got %d invitations of the bidder, want only %s", len(invitations), invitation.Id)
+		}
+
+		input := BidInput{Name: "Offer", Description: "Private offer", TenderId: open.Id, AuthorType: "User", AuthorId: s.userId(bidder)}
+		s.do(t, "POST", "/api/bids/new", nil, input).expect(t, http.StatusForbidden, CodeNotInvited)
+
+		accept := "/api/invitations/" + invitation.Id + "/accept"
+		s.do(t, "PUT", accept, user(outsider), nil).expect(t, http.StatusForbidden, CodeNotInvitee)
+		s.do(t, "PUT", "/api/invitations/"+uuid.NewString()+"/accept", user(bidder), nil).expect(t, http.StatusNotFound, CodeInvitationNotFound)
+		s.do(t, "PUT", accept, user(bidder2), nil).expect(t, http.StatusOK, "")
+
+		s.do(t, "GET", path, user(bidder), nil).expect(t, http.StatusOK, "")
+		s.do(t, "POST", "/api/bids/new", nil, input).expect(t, http.StatusOK, "")
+		outsiderBid := input
+		outsiderBid.AuthorId = s.userId(outsider)
+		s.do(t, "POST", "/api/bids/new", nil, outsiderBid).expect(t, http.StatusForbidden, CodeNotInvited)
+	})
+
+	t.Run("suppliers", func(t *testing.T) {
+		open := s.createTender(t, owner, s.organization(0), "Delivery")
+		s.publishTender(t, owner, open.Id)
+
+		block := supplierEntryInput{List: data.SupplierBlockList, SubjectType: "Organization", SubjectId: s.organization(2), Reason: "Late deliveries"}
+		s.do(t, "POST", "/api/suppliers/new", user("nobody"), block).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		s.do(t, "POST", "/api/suppliers/new", user(loner), block).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "POST", "/api/suppliers/new", user(owner), supplierEntryInput{List: "grey", SubjectType: "User", SubjectId: uuid.NewString(), Reason: "?"}).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+		var entry data.SupplierEntry
+		s.do(t, "POST", "/api/suppliers/new", user(owner), block).expect(t, http.StatusOK, "").decode(t, &entry)
+		s.do(t, "POST", "/api/suppliers/new", user(owner), block).expect(t, http.StatusConflict, CodeSupplierEntryExists)
+
+		var entries []data.SupplierEntry
+		s.do(t, "GET", "/api/suppliers", user(owner, "list", data.SupplierBlockList), nil).expect(t, http.StatusOK, "").decode(t, &entries)
+		if len(entries) != 1 || entries[0].Id != entry.Id {
+			t.Errorf("got %d blocklist entries, want only %s", len(entries), entry.Id)
+		}
+		s.do(t, "GET", "/api/suppliers", user(outsider), nil).expect(t, http.StatusOK, "").decode(t, &entries)
+		if len(entries) != 0 {
+			t.Errorf("outsider sees %d entries of another organization", len(entries))
+		}
+
+		entryPath := "/api/suppliers/" + entry.Id
+		edit := supplierEntryEditInput{Reason: "Repeatedly late deliveries"}
+		s.do(t, "PATCH", entryPath, user(outsider), edit).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PATCH", entryPath, user(owner2), edit).expect(t, http.StatusOK, "")
+
+		blocked := BidInput{Name: "Offer", Description: "Blocked", TenderId: open.Id, AuthorType: "User", AuthorId: s.userId(outsider)}
+		s.do(t, "POST", "/api/bids/new", nil, blocked).expect(t, http.StatusForbidden, CodeSupplierBlocked)
+
+		s.do(t, "DELETE", entryPath, user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "DELETE", entryPath, user(owner), nil).expect(t, http.StatusNoContent, "")
+		s.do(t, "DELETE", entryPath, user(owner), nil).expect(t, http.StatusNotFound, CodeSupplierEntryNotFound)
+
+		requirement := "/api/suppliers/requirements/Delivery"
+		s.do(t, "PUT", "/api/suppliers/requirements/Astrology", user(owner), nil).expect(t, http.StatusNotFound, CodeServiceTypeUnknown)
+		s.do(t, "PUT", requirement, user(loner), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PUT", requirement, user(owner), nil).expect(t, http.StatusNoContent, "")
+
+		var requirements []string
+		s.do(t, "GET", "/api/suppliers/requirements", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &requirements)
+		if len(requirements) != 1 || requirements[0] != "Delivery" {
+			t.Errorf("got requirements %v, want [Delivery]", requirements)
+		}
+
+		unqualified := BidInput{Name: "Offer", Description: "Unqualified", TenderId: open.Id, AuthorType: "Organization", AuthorId: s.organization(1)}
+		s.do(t, "POST", "/api/bids/new", nil, unqualified).expect(t, http.StatusForbidden, CodeSupplierNotQualified)
+
+		allow := supplierEntryInput{List: data.SupplierAllowList, SubjectType: "Organization", SubjectId: s.organization(1), Reason: "Certified carrier"}
+		s.do(t, "POST", "/api/suppliers/new", user(owner), allow).expect(t, http.StatusOK, "")
+		s.do(t, "POST", "/api/bids/new", nil, unqualified).expect(t, http.StatusOK, "")
+
+		s.do(t, "DELETE", requirement, user(owner), nil).expect(t, http.StatusNoContent, "")
+		s.do(t, "DELETE", requirement, user(owner), nil).expect(t, http.StatusNotFound, CodeRequirementNotFound)
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		input := templateInput{Name: "Monthly delivery", Description: "Regular parcel delivery", ServiceType: "Delivery"}
+		s.do(t, "POST", "/api/templates/new", user("nobody"), input).expect(t, http.StatusUnauthorized, CodeUserNotFound)
+		s.do(t, "POST", "/api/templates/new", user(loner), input).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "POST", "/api/templates/new", user(owner), templateInput{Name: "No service"}).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+
+		var template data.Template
+		s.do(t, "POST", "/api/templates/new", user(owner), input).expect(t, http.StatusOK, "").decode(t, &template)
+
+		var templates []data.Template
+		s.do(t, "GET", "/api/templates", user(owner2), nil).expect(t, http.StatusOK, "").decode(t, &templates)
+		if len(templates) != 1 || templates[0].Id != template.Id {
+			t.Errorf("got %d templates, want only %s", len(templates), template.Id)
+		}
+
+		path := "/api/templates/" + template.Id
+		s.do(t, "GET", path, user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusOK, "")
+		s.do(t, "PATCH", path, user(outsider), templateEditInput{Name: "Weekly delivery"}).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "PATCH", path, user(owner), templateEditInput{Name: "Weekly delivery"}).expect(t, http.StatusOK, "").decode(t, &template)
+		if template.Name != "Weekly delivery" {
+			t.Errorf("got name %q after edit", template.Name)
+		}
+
+		s.do(t, "POST", path+"/createFromTemplate", user(outsider), nil).expect(t, http.StatusNotFound, CodeTemplateNotFound)
+		var created tenderResponse
+		s.do(t, "POST", path+"/createFromTemplate", user(owner), nil).expect(t, http.StatusOK, "").decode(t, &created)
+		if created.Name != template.Name || created.Status != "Created" {
+			t.Errorf("unexpected tender from template %+v", created)
+		}
+
+		s.do(t, "DELETE", path, user(outsider), nil).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "DELETE", path, user(owner), nil).expect(t, http.StatusNoContent, "")
+		s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusNotFound, CodeTemplateNotFound)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		items := []tenderInput{
+			{Name: "Bulk one", Description: "First", ServiceType: "Delivery", OrganizationID: s.organization(0)},
+			{Name: "", Description: "Invalid", ServiceType: "Delivery", OrganizationID: s.organization(0)},
+			{Name: "Bulk three", Description: "Foreign", ServiceType: "Delivery", OrganizationID: s.organization(1)},
+		}
+
+		var res bulkResponse
+		s.do(t, "POST", "/api/tenders/bulk/new", user(loner), items).expect(t, http.StatusForbidden, CodeNoRights)
+		s.do(t, "POST", "/api/tenders/bulk/new", user(owner, "mode", bulkAtomic), items).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Committed || res.Succeeded != 0 {
+			t.Errorf("atomic bulk with failures committed %d items", res.Succeeded)
+		}
+
+		s.do(t, "POST", "/api/tenders/bulk/new", user(owner), items).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Mode != bulkPartial || res.Succeeded != 1 || res.Failed != 2 {
+			t.Fatalf("unexpected partial bulk result %+v", res)
+		}
+		codes := []string{res.Results[0].Code, res.Results[1].Code, res.Results[2].Code}
+		if codes[0] != "" || codes[1] != CodeValidationFailed || codes[2] != CodeNoRights {
+			t.Errorf("got item codes %v", codes)
+		}
+		created, _ := json.Marshal(res.Results[0].Result)
+		var bulkTender tenderResponse
+		json.Unmarshal(created, &bulkTender)
+
+		statuses := []bulkStatusItem{
+			{TenderId: bulkTender.Id, Status: "Published"},
+			{TenderId: tender.Id, Status: "Archived"},
+			{TenderId: uuid.NewString(), Status: "Published"},
+		}
+		s.do(t, "POST", "/api/tenders/bulk/status", user(outsider), statuses[:1]).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Results[0].Code != CodeNoRights {
+			t.Errorf("outsider changed the status of a tender: %+v", res.Results[0])
+		}
+		s.do(t, "POST", "/api/tenders/bulk/status", user(owner), statuses).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Succeeded != 1 || res.Results[1].Code != CodeValidationFailed || res.Results[2].Code != CodeTenderNotFound {
+			t.Errorf("unexpected bulk status result %+v", res)
+		}
+
+		first := s.createBid(t, bulkTender.Id, "User", s.userId(bidder))
+		second := s.createBid(t, bulkTender.Id, "Organization", s.organization(2))
+		s.publishBid(t, bidder, first.Id)
+		s.publishBid(t, outsider, second.Id)
+
+		decisions := []bulkDecisionItem{
+			{BidId: first.Id, Decision: "Approved"},
+			{BidId: second.Id, Decision: "Rejected"},
+			{BidId: uuid.NewString(), Decision: "Approved"},
+			{BidId: first.Id, Decision: "Maybe"},
+		}
+		s.do(t, "POST", "/api/bids/bulk/decision", user(loner), decisions).expect(t, http.StatusUnauthorized, CodeOrganizationNotFound)
+		s.do(t, "POST", "/api/bids/bulk/decision", user(owner), decisions).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Succeeded != 2 || res.Results[2].Code != CodeBidNotFound || res.Results[3].Code != CodeValidationFailed {
+			t.Errorf("unexpected bulk decision result %+v", res)
+		}
+
+		// A bulk approval that reaches the quorum closes the tender too.
+		s.do(t, "POST", "/api/bids/bulk/decision", user(owner2), decisions[:1]).expect(t, http.StatusOK, "")
+		s.do(t, "POST", "/api/bids/bulk/decision", user(owner3), decisions[:1]).expect(t, http.StatusOK, "")
+		if status := s.tenderStatus(t, owner, bulkTender.Id); status != "Closed" {
+			t.Errorf("tender is %s after bulk approvals reached the quorum", status)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		csv := "externalId,name,description,serviceType\next-1,Imported tender,From the ERP,Delivery\n"
+		header := http.Header{"Content-Type": {"text/csv"}}
+
+		var res bulkResponse
+		s.doWithHeader(t, "POST", "/api/tenders/import", user(loner), csv, header).expect(t, http.StatusForbidden, CodeNoRights)
+		s.doWithHeader(t, "POST", "/api/tenders/import", user(owner, "format", "xml"), csv, header).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.doWithHeader(t, "POST", "/api/tenders/import", user(owner, "dryRun", "true"), csv, header).expect(t, http.StatusOK, "").decode(t, &res)
+		if !res.DryRun || res.Committed || res.Succeeded != 1 {
+			t.Errorf("unexpected dry run result %+v", res)
+		}
+
+		var results []importResult
+		for i, created := range []bool{true, false} {
+			s.doWithHeader(t, "POST", "/api/tenders/import", user(owner), csv, header).expect(t, http.StatusOK, "").decode(t, &res)
+			js, _ := json.Marshal(res.Results[0].Result)
+			var result importResult
+			json.Unmarshal(js, &result)
+			if res.Succeeded != 1 || result.Created != created {
+				t.Errorf("import %d: got %+v, created %v", i+1, res, result.Created)
+			}
+			results = append(results, result)
+		}
+		if results[0].TenderId != results[1].TenderId {
+			t.Error("importing the same row again created another tender")
+		}
+
+		ndjson := `{"externalId":"ext-2","name":"NDJSON tender","description":"From the ERP","serviceType":"Construction"}` + "\n"
+		s.do(t, "POST", "/api/tenders/import", user(owner), ndjson).expect(t, http.StatusOK, "").decode(t, &res)
+		if res.Succeeded != 1 {
+			t.Errorf("unexpected NDJSON import result %+v", res)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		res := s.do(t, "GET", "/api/tenders/my/export", user(owner), nil).expect(t, http.StatusOK, "")
+		if !strings.HasPrefix(res.header.Get("Content-Type"), "text/csv") {
+			t.Errorf("got content type %s", res.header.Get("Content-Type"))
+		}
+		if !strings.Contains(string(res.body), tender.Id) {
+			t.Error("tender export does not contain the tender")
+		}
+		s.do(t, "GET", "/api/tenders/my/export", user(owner, "format", "pdf"), nil).expect(t, http.StatusUnprocessableEntity, CodeValidationFailed)
+		s.do(t, "GET", "/api/tenders/my/export", user(loner), nil).expect(t, http.StatusForbidden, CodeOrganizationNotFound)
+		s.do(t, "GET", "/api/tenders/my/export", user(owner, "format", "xlsx"), nil).expect(t, http.StatusOK, "")
+
+		path := fmt.Sprintf("/api/bids/%s/export", tender.Id)
+		s.do(t, "GET", path, user(outsider), nil).expect(t, http.StatusForbidden, CodeNotTenderResponsible)
+		s.do(t, "GET", fmt.Sprintf("/api/bids/%s/export", uuid.NewString()), user(owner), nil).expect(t, http.StatusNotFound, CodeTenderNotFound)
+		res = s.do(t, "GET", path, user(owner), nil).expect(t, http.StatusOK, "")
+		if !strings.Contains(string(res.body), bid.Id) {
+			t.Error("bid export does not contain the bid")
+		}
+	})
+
+	t.Run("idempotency", func(t *testing.T) {
+		input := templateInput{Name: "Idempotent", Description: "Sent twice", ServiceType: "Delivery"}
+		header := http.Header{"Idempotency-Key": {"template-1"}}
+
+		first := s.doWithHeader(t, "POST", "/api/templates/new", user(owner), input, header).expect(t, http.StatusOK, "")
+		second := s.doWithHeader(t, "POST", "/api/templates/new", user(owner), input, header).expect(t, http.StatusOK, "")
+		if second.header.Get(idempotencyReplayed) != "true" || !bytes.Equal(first.body, second.body) {
+			t.Error("repeated request was not replayed")
+		}
+
+		// Keys are scoped to the caller, so another user's key does not clash.
+		other := s.doWithHeader(t, "POST", "/api/templates/new", user(owner2), input, header).expect(t, http.StatusOK, "")
+		if other.header.Get(idempotencyReplayed) != "" {
+			t.Error("request of another user was replayed")
+		}
+
+		input.Name = "Changed"
+		s.doWithHeader(t, "POST", "/api/templates/new", user(owner), input, header).expect(t, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused)
+
+		// Creating a tender names the caller in the body rather than in the
+		// query, and keys are still scoped to them.
+		header = http.Header{"Idempotency-Key": {"tender-1"}}
+		tender := tenderInput{Name: "Idempotent", Description: "Sent twice", ServiceType: "Delivery", OrganizationID: s.organization(0), CreatorUsername: owner}
+		s.doWithHeader(t, "POST", "/api/tenders/new", nil, tender, header).expect(t, http.StatusOK, "")
+		tender.CreatorUsername = owner2
+		other = s.doWithHeader(t, "POST", "/api/tenders/new", nil, tender, header).expect(t, http.StatusOK, "")
+		if other.header.Get(idempotencyReplayed) != "" {
+			t.Error("tender of another creator was replayed")
+		}
+	})
+
+	suite := t
+	t.Run("every route is covered", func(t *testing.T) {
+		if flag.Lookup("test.run").Value.String() != "" || suite.Failed() {
+			t.Skip("only checked when the whole suite runs")
+		}
+		for _, route := range routeOperations(s.app.router()) {
+			if !s.routes[route] {
+				t.Errorf("route %s is not covered by the integration tests", route)
+			}
+		}
+	})
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/validator"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type invitationInput struct {
+	InviteeType string `json:"inviteeType"`
+	InviteeId   string `json:"inviteeId"`
+}
+
+func invitationInputRules() validator.FieldRules {
+	return validator.FieldRules{
+		"inviteeType": {validator.Required(), validator.OneOf("User", "Organization")},
+		"inviteeId":   {validator.Required(), validator.UUID()},
+	}
+}
+
+func (input invitationInput) validate(v *validator.Validator) {
+	v.Struct(input, invitationInputRules())
+}
+
+// viewerIds returns the ids an invitation may be addressed to for the user:
+// the user and the organization they are responsible for, if any.
+func (app *application) viewerIds(userId string) ([]string, error) {
+	ids := []string{userId}
+
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			return ids, nil
+		}
+		return nil, err
+	}
+	return append(ids, organizationId), nil
+}
+
+// tenderOwner checks that the user is responsible for the organization that
+// owns the tender, answering with an error itself otherwise.
+func (app *application) tenderOwner(w http.ResponseWriter, r *http.Request, tenderId, username string) bool {
+	tenderOrganizationId, err := app.models.Tenders.GetTenderOrganization(tenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return false
+		}
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return false
+	}
+
+	if organizationId != tenderOrganizationId {
+		app.forbiddenResponse(w, r, data.ErrNoRights)
+		return false
+	}
+	return true
+}
+
+func (app *application) changeVisibilityHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	tenderId := mux.Vars(r)["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+	visibility := readQueryValue(q, "visibility", v)
+	v.Field("visibility", visibility, validator.Required(), validator.OneOf(data.Visibilities...))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.tenderOwner(w, r, tenderId, username) {
+		return
+	}
+
+	tender, err := app.models.Tenders.ChangeTenderVisibility(tenderId, visibility)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, tender, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) createInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	tenderId := mux.Vars(r)["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	var input invitationInput
+	err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.tenderOwner(w, r, tenderId, username) {
+		return
+	}
+
+	var exists bool
+	if input.InviteeType == "User" {
+		exists, err = app.models.Tenders.UserExists(input.InviteeId)
+	} else {
+		exists, err = app.models.Tenders.OrganizationExists(input.InviteeId)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		v.AddMessage("inviteeId", CodeInviteeNotFound)
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	invitation := data.Invitation{
+		Id:          uuid.New().String(),
+		TenderId:    tenderId,
+		InviteeType: input.InviteeType,
+		InviteeId:   input.InviteeId,
+		Status:      "Pending",
+		CreatedAt:   time.Now().Format(time.RFC3339),
+	}
+
+	err = app.models.Invitations.InsertInvitation(&invitation)
+	if err != nil {
+		if errors.Is(err, data.ErrInvitationExists) {
+			app.conflictResponse(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, invitation, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getTenderInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	tenderId := mux.Vars(r)["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.tenderOwner(w, r, tenderId, username) {
+		return
+	}
+
+	invitations, err := app.models.Invitations.GetTenderInvitations(tenderId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, invitations, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	vars := mux.Vars(r)
+	tenderId := vars["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+	invitationId := vars["invitationId"]
+	_, err = uuid.Parse(invitationId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrInvitationNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.tenderOwner(w, r, tenderId, username) {
+		return
+	}
+
+	err = app.models.Invitations.DeleteInvitation(tenderId, invitationId)
+	if err != nil {
+		if errors.Is(err, data.ErrInvitationNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getMyInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	inviteeIds, err := app.viewerIds(userId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	invitations, err := app.models.Invitations.GetInviteeInvitations(inviteeIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, invitations, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// acceptInvitationHandler lets the invited user, or a responsible of the
+// invited organization, accept an invitation.
+func (app *application) acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	invitationId := mux.Vars(r)["invitationId"]
+	_, err := uuid.Parse(invitationId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrInvitationNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	invitation, err := app.models.Invitations.GetInvitation(invitationId)
+	if err != nil {
+		if errors.Is(err, data.ErrInvitationNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return
+	}
+
+	inviteeIds, err := app.viewerIds(userId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !containsString(inviteeIds, invitation.InviteeId) {
+		app.forbiddenResponse(w, r, ErrNotInvitee)
+		return
+	}
+
+	invitation, err = app.models.Invitations.AcceptInvitation(invitationId)
+	if err != nil {
+		if errors.Is(err, data.ErrInvitationNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, invitation, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -1,100 +1,116 @@
 package main
 
 import (
+	"avitotask/internal/config"
 	"avitotask/internal/data"
+	"avitotask/internal/ratelimit"
 	"context"
 	"database/sql"
-	"fmt"
-	"log"
-	"net/http"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
-	"time"
 
 	_ "github.com/lib/pq"
 )
 
-type config struct {
-	addr string
-	db   struct {
-		serverAddress    string
-		postgresConn     string
-		postgresJDBC     string
-		postgresUsername string
-		postgresPassword string
-		postgresHost     string
-		postgresPort     string
-		postgresDB       string
-	}
-}
-
 type application struct {
-	config config
-	models data.Models
+	config  config.Config
+	logger  *slog.Logger
+	models  data.Models
+	workers *workerGroup
+	limiter ratelimit.Store
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(importCommand(os.Args[2:], os.Getenv, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(adminCommand(os.Args[2:], os.Getenv, os.Stdout, os.Stderr))
+	}
 
-	cfg := config{}
+	cfg, opts, err := config.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		slog.Error("invalid configuration", slog.String("error", err.Error()))
+		os.Exit(2)
+	}
 
-	cfg.addr = os.Getenv("SERVER_ADDRESS")
-	if cfg.addr == "" {
-		cfg.addr = ":8080"
+	if opts.PrintConfig {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		enc.Encode(cfg.Redacted())
+		return
 	}
-	cfg.db.postgresConn = os.Getenv("POSTGRES_CONN")
-	cfg.db.postgresJDBC = os.Getenv("POSTGRES_JDBC_URL")
-	cfg.db.postgresUsername = os.Getenv("POSTGRES_USERNAME")
-	cfg.db.postgresPassword = os.Getenv("POSTGRES_PASSWORD")
-	cfg.db.postgresHost = os.Getenv("POSTGRES_HOST")
-	cfg.db.postgresPort = os.Getenv("POSTGRES_PORT")
-	cfg.db.postgresDB = os.Getenv("POSTGRES_DATABASE")
+
+	logger := newLogger(cfg)
+
 	db, err := openDB(cfg)
 	if err != nil {
-		cfg.db.postgresConn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s", cfg.db.postgresUsername, cfg.db.postgresPassword, cfg.db.postgresHost, cfg.db.postgresPort, cfg.db.postgresDB)
-		db, err = openDB(cfg)
-	}
-	//fmt.Println(cfg.db.postgresConn)
-	if err != nil {
-		log.Fatal(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
-	fmt.Println("Started a db connection pool")
-	defer db.Close()
+	logger.Info("started a db connection pool")
 
 	app := &application{
-		config: cfg,
-		models: data.NewModels(db),
+		config:  cfg,
+		logger:  logger,
+		models:  data.NewModels(db),
+		workers: newWorkerGroup(),
+		limiter: ratelimit.NewMemoryStore(cfg.RateLimit.MaxBuckets),
 	}
 
 	err = app.models.Tables.CreateTables()
 
 	if err != nil {
-		log.Println(err)
-	}
-
-	srv := &http.Server{
-		Addr:    cfg.addr,
-		Handler: app.routes(),
+		logger.Error("failed to create tables", slog.String("error", err.Error()))
 	}
 
-	err = srv.ListenAndServe()
+	app.startRateLimitCleanup()
+	app.startIdempotencyCleanup()
 
+	err = app.serve()
 	if err != nil {
-		log.Fatal(err)
+		logger.Error(err.Error())
+		db.Close()
+		os.Exit(1)
+	}
+
+	if err = db.Close(); err != nil {
+		logger.Error("failed to close db connection pool", slog.String("error", err.Error()))
 	}
+	logger.Info("stopped db connection pool")
 }
 
-func openDB(cfg config) (*sql.DB, error) {
+func newLogger(cfg config.Config) *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
 
-	db, err := sql.Open("postgres", cfg.db.postgresConn)
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+func openDB(cfg config.Config) (*sql.DB, error) {
+
+	db, err := sql.Open("postgres", cfg.DB.ConnectionString())
 	if err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	db.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	db.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime.Std())
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DB.ConnectTimeout.Std())
 	defer cancel()
 
 	err = db.PingContext(ctx)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
@@ -0,0 +1,725 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/export"
+	"avitotask/internal/openapi"
+	"avitotask/internal/validator"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+type paramDoc struct {
+	name        string
+	required    bool
+	description string
+	schema      *openapi.Schema
+}
+
+type routeDoc struct {
+	summary  string
+	tag      string
+	query    []paramDoc
+	body     any
+	response any
+	statuses []int
+}
+
+// textResponse, htmlResponse, reportResponse and noContentResponse mark
+// routes that do not answer with JSON.
+type textResponse struct{}
+type htmlResponse struct{}
+type reportResponse struct{}
+type noContentResponse struct{}
+
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+var (
+	usernameParam = paramDoc{name: "username", required: true, description: "responsible user", schema: openapi.String()}
+	limitParam    = paramDoc{name: "limit", description: "page size", schema: &openapi.Schema{Type: "integer", Format: "int32", Minimum: intPtr(1)}}
+	deadlineParam = paramDoc{name: "deadline", description: "bid deadline of the new tender", schema: &openapi.Schema{Type: "string", Format: "date-time"}}
+	bulkModeParam = paramDoc{name: "mode", description: "atomic commits all items or none, partial commits every item on its own", schema: openapi.Enum(bulkAtomic, bulkPartial)}
+	offsetParam   = paramDoc{name: "offset", description: "number of items to skip", schema: &openapi.Schema{Type: "integer", Format: "int32", Minimum: intPtr(0)}}
+)
+
+var exportParams = []paramDoc{
+	{name: "format", description: "report format, csv by default", schema: openapi.Enum(export.Formats...)},
+	{name: "include", description: "comma separated optional columns: history adds a row per previous version, approvals adds approval counts", schema: openapi.String()},
+	{name: "limit", description: "maximum number of items, all by default", schema: &openapi.Schema{Type: "integer", Format: "int32", Minimum: intPtr(1)}},
+	offsetParam,
+	usernameParam,
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+var routeDocs = map[string]routeDoc{
+	"GET /api/ping": {summary: "Check that the server is up", tag: "health", response: textResponse{}},
+	"GET /healthz":  {summary: "Liveness probe", tag: "health", response: envelope{}},
+	"GET /readyz":   {summary: "Readiness probe with per-component breakdown", tag: "health", response: envelope{}, statuses: []int{http.StatusServiceUnavailable}},
+
+	"GET /api/statuses": {summary: "Localized display names of tender and bid statuses", tag: "reference", response: envelope{}},
+
+	"GET /api/service-types": {
+		summary:  "Service type catalogue with names in the requested language",
+		tag:      "reference",
+		query:    []paramDoc{{name: "includeInactive", description: "also list deactivated types", schema: openapi.Enum("true", "false")}},
+		response: []*data.ServiceType{},
+		statuses: []int{http.StatusBadRequest},
+	},
+	"POST /api/service-types/new": {
+		summary:  "Add a service type (admin)",
+		tag:      "reference",
+		query:    []paramDoc{usernameParam},
+		body:     serviceTypeInput{},
+		response: data.ServiceType{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+	},
+	"PATCH /api/service-types/{serviceType}": {
+		summary:  "Change parent, names or active flag of a service type (admin)",
+		tag:      "reference",
+		query:    []paramDoc{usernameParam},
+		body:     serviceTypeEditInput{},
+		response: data.ServiceType{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"DELETE /api/service-types/{serviceType}": {
+		summary:  "Delete an unused service type (admin)",
+		tag:      "reference",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+
+	"GET /api/openapi.json": {summary: "This OpenAPI document", tag: "docs", response: envelope{}},
+	"GET /api/docs":         {summary: "Human-readable API documentation", tag: "docs", response: htmlResponse{}},
+
+	"GET /api/tenders": {
+		summary: "List published tenders",
+		tag:     "tenders",
+		query: []paramDoc{limitParam, offsetParam,
+			{name: "username", description: "also list private tenders the user is invited to", schema: openapi.String()},
+			{name: "service_type", description: "filter by service type codes, a category matches its subcategories", schema: openapi.ArrayOf(openapi.String())}},
+		response: []*data.Tender{},
+		statuses: []int{http.StatusBadRequest},
+	},
+	"POST /api/tenders/new": {
+		summary:  "Create a tender",
+		tag:      "tenders",
+		body:     tenderInput{},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"GET /api/tenders/my": {
+		summary:  "List tenders of the user's organization",
+		tag:      "tenders",
+		query:    []paramDoc{limitParam, offsetParam, usernameParam},
+		response: []*data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"GET /api/tenders/my/export": {
+		summary:  "Download tenders of the user's organization as CSV or XLSX",
+		tag:      "export",
+		query:    exportParams,
+		response: reportResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"POST /api/tenders/bulk/new": {
+		summary:  "Create tenders from a JSON array or NDJSON",
+		tag:      "bulk",
+		query:    []paramDoc{usernameParam, bulkModeParam},
+		body:     []tenderInput{},
+		response: bulkResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"POST /api/tenders/bulk/status": {
+		summary:  "Change the status of many tenders",
+		tag:      "bulk",
+		query:    []paramDoc{usernameParam, bulkModeParam},
+		body:     []bulkStatusItem{},
+		response: bulkResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"POST /api/tenders/import": {
+		summary: "Import tenders from CSV or NDJSON, skipping external ids imported before",
+		tag:     "bulk",
+		query: []paramDoc{usernameParam, bulkModeParam,
+			{name: "format", description: "body format, by default csv for text/csv bodies and ndjson otherwise", schema: openapi.Enum(importCSV, importNDJSON)},
+			{name: "dryRun", description: "check every row without saving anything", schema: openapi.Enum("true", "false")}},
+		body:     []tenderImportItem{},
+		response: bulkResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"GET /api/tenders/{tenderId}": {
+		summary:  "Get a tender; its organization also sees bid statistics",
+		tag:      "tenders",
+		query:    []paramDoc{{name: "username", description: "viewer, required for private and unpublished tenders", schema: openapi.String()}},
+		response: tenderDetail{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+	},
+	"GET /api/tenders/{tenderId}/status": {
+		summary:  "Get tender status",
+		tag:      "tenders",
+		query:    []paramDoc{usernameParam},
+		response: textResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/tenders/{tenderId}/status": {
+		summary:  "Change tender status",
+		tag:      "tenders",
+		query:    []paramDoc{{name: "status", required: true, schema: openapi.Enum(mapKeys(availableStatuses)...)}, usernameParam},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PATCH /api/tenders/{tenderId}/edit": {
+		summary:  "Edit a tender and bump its version",
+		tag:      "tenders",
+		query:    []paramDoc{usernameParam},
+		body:     tenderEditInput{},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/tenders/{tenderId}/rollback/{version}": {
+		summary:  "Roll a tender back to an earlier version",
+		tag:      "tenders",
+		query:    []paramDoc{usernameParam},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"POST /api/tenders/{tenderId}/clone": {
+		summary: "Create a tender from the current or an earlier version of another",
+		tag:     "tenders",
+		query: []paramDoc{usernameParam,
+			{name: "version", description: "version to clone, the current one by default", schema: &openapi.Schema{Type: "integer", Minimum: intPtr(1)}},
+			deadlineParam},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/tenders/{tenderId}/visibility": {
+		summary:  "Change who can see and bid on a tender",
+		tag:      "invitations",
+		query:    []paramDoc{{name: "visibility", required: true, schema: openapi.Enum(data.Visibilities...)}, usernameParam},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/tenders/{tenderId}/invitations": {
+		summary:  "List invitations to a tender",
+		tag:      "invitations",
+		query:    []paramDoc{usernameParam},
+		response: []*data.Invitation{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"POST /api/tenders/{tenderId}/invitations": {
+		summary:  "Invite a user or an organization to a tender",
+		tag:      "invitations",
+		query:    []paramDoc{usernameParam},
+		body:     invitationInput{},
+		response: data.Invitation{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	"DELETE /api/tenders/{tenderId}/invitations/{invitationId}": {
+		summary:  "Revoke an invitation",
+		tag:      "invitations",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/invitations/my": {
+		summary:  "Invitations addressed to the user or their organization",
+		tag:      "invitations",
+		query:    []paramDoc{usernameParam},
+		response: []*data.Invitation{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	"PUT /api/invitations/{invitationId}/accept": {
+		summary:  "Accept an invitation",
+		tag:      "invitations",
+		query:    []paramDoc{usernameParam},
+		response: data.Invitation{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+
+	"GET /api/suppliers": {
+		summary: "Supplier allowlist and blocklist of the user's organization",
+		tag:     "suppliers",
+		query: []paramDoc{limitParam, offsetParam, usernameParam,
+			{name: "list", description: "only one of the lists", schema: openapi.Enum(data.SupplierAllowList, data.SupplierBlockList)},
+			{name: "includeExpired", description: "also list expired entries", schema: openapi.Enum("true", "false")}},
+		response: []*data.SupplierEntry{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"POST /api/suppliers/new": {
+		summary:  "Put a user or an organization on the allowlist or the blocklist",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		body:     supplierEntryInput{},
+		response: data.SupplierEntry{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict},
+	},
+	"PATCH /api/suppliers/{entryId}": {
+		summary:  "Change the reason or the expiry of a list entry",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		body:     supplierEntryEditInput{},
+		response: data.SupplierEntry{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"DELETE /api/suppliers/{entryId}": {
+		summary:  "Remove a list entry",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/suppliers/requirements": {
+		summary:  "Service types that require a qualified supplier",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		response: []string{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"PUT /api/suppliers/requirements/{serviceType}": {
+		summary:  "Require qualification for tenders of a service type",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"DELETE /api/suppliers/requirements/{serviceType}": {
+		summary:  "Stop requiring qualification for a service type",
+		tag:      "suppliers",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+
+	"GET /api/templates": {
+		summary:  "List tender templates of the user's organization",
+		tag:      "templates",
+		query:    []paramDoc{limitParam, offsetParam, usernameParam},
+		response: []*data.Template{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"POST /api/templates/new": {
+		summary:  "Create a tender template",
+		tag:      "templates",
+		query:    []paramDoc{usernameParam},
+		body:     templateInput{},
+		response: data.Template{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+	},
+	"GET /api/templates/{templateId}": {
+		summary:  "Get a tender template",
+		tag:      "templates",
+		query:    []paramDoc{usernameParam},
+		response: data.Template{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PATCH /api/templates/{templateId}": {
+		summary:  "Edit a tender template",
+		tag:      "templates",
+		query:    []paramDoc{usernameParam},
+		body:     templateEditInput{},
+		response: data.Template{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"DELETE /api/templates/{templateId}": {
+		summary:  "Delete a tender template",
+		tag:      "templates",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"POST /api/templates/{templateId}/createFromTemplate": {
+		summary:  "Create a tender from a template",
+		tag:      "templates",
+		query:    []paramDoc{usernameParam, deadlineParam},
+		response: data.Tender{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+
+	"POST /api/bids/new": {
+		summary:  "Create a bid",
+		tag:      "bids",
+		body:     BidInput{},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/bids/my": {
+		summary:  "List bids of the user",
+		tag:      "bids",
+		query:    []paramDoc{limitParam, offsetParam, usernameParam},
+		response: []*data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	"POST /api/bids/bulk/decision": {
+		summary:  "Submit decisions on many bids",
+		tag:      "bulk",
+		query:    []paramDoc{usernameParam, bulkModeParam},
+		body:     []bulkDecisionItem{},
+		response: bulkResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	"GET /api/bids/{bidId}": {
+		summary:  "Get a bid; its responsibles also see the description",
+		tag:      "bids",
+		query:    []paramDoc{usernameParam},
+		response: bidDetail{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/bids/{bidId}/status": {
+		summary:  "Get bid status",
+		tag:      "bids",
+		query:    []paramDoc{usernameParam},
+		response: textResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/bids/{bidId}/status": {
+		summary:  "Change bid status",
+		tag:      "bids",
+		query:    []paramDoc{{name: "status", required: true, schema: openapi.Enum(mapKeys(availableBidStatuses)...)}, usernameParam},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/bids/{tenderId}/list": {
+		summary:  "List published bids for a tender",
+		tag:      "bids",
+		query:    []paramDoc{limitParam, offsetParam, usernameParam},
+		response: []*data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/bids/{tenderId}/export": {
+		summary:  "Download published bids for a tender as CSV or XLSX",
+		tag:      "export",
+		query:    exportParams,
+		response: reportResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PATCH /api/bids/{bidId}/edit": {
+		summary:  "Edit a bid and bump its version",
+		tag:      "bids",
+		query:    []paramDoc{usernameParam},
+		body:     bidEditInput{},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	"PUT /api/bids/{bidId}/rollback/{version}": {
+		summary:  "Roll a bid back to an earlier version",
+		tag:      "bids",
+		query:    []paramDoc{usernameParam},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+	},
+	"PUT /api/bids/{bidId}/submit_decision": {
+		summary:  "Approve or reject a bid",
+		tag:      "bids",
+		query:    []paramDoc{{name: "decision", required: true, schema: openapi.Enum("Approved", "Rejected")}, usernameParam},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/bids/{bidId}/withdraw": {
+		summary: "Withdraw a bid from review",
+		tag:     "bids",
+		query: []paramDoc{usernameParam,
+			{name: "override", description: "tender owner override after the deadline", schema: openapi.Enum("true", "false")}},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"PUT /api/bids/{bidId}/resubmit": {
+		summary:  "Publish a withdrawn bid again as a new version",
+		tag:      "bids",
+		query:    []paramDoc{usernameParam},
+		response: data.Bid{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/bids/{bidId}/approvals": {
+		summary:  "List approvals of a bid across versions",
+		tag:      "approvals",
+		query:    []paramDoc{usernameParam},
+		response: []*data.Approval{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"DELETE /api/bids/{bidId}/approvals/me": {
+		summary:  "Revoke the user's approval of the current bid version",
+		tag:      "approvals",
+		query:    []paramDoc{usernameParam},
+		response: noContentResponse{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+	"GET /api/decisions/pending": {
+		summary:  "Bids awaiting the user's decision",
+		tag:      "approvals",
+		query:    []paramDoc{limitParam, offsetParam, usernameParam},
+		response: []*data.PendingDecision{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized},
+	},
+	"GET /api/bids/{bidId}/approvals/versions": {
+		summary:  "Approval counts per bid version",
+		tag:      "approvals",
+		query:    []paramDoc{usernameParam},
+		response: []*data.VersionApprovals{},
+		statuses: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+	},
+}
+
+var pathParamRegexp = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+var schemaNames = map[string]string{
+	"data.Tender":                 "Tender",
+	"data.Bid":                    "Bid",
+	"main.tenderInput":            "TenderInput",
+	"main.BidInput":               "BidInput",
+	"main.tenderEditInput":        "TenderEditInput",
+	"main.serviceTypeInput":       "ServiceTypeInput",
+	"main.serviceTypeEditInput":   "ServiceTypeEditInput",
+	"data.ServiceType":            "ServiceType",
+	"main.invitationInput":        "InvitationInput",
+	"data.Invitation":             "Invitation",
+	"main.supplierEntryInput":     "SupplierEntryInput",
+	"main.supplierEntryEditInput": "SupplierEntryEditInput",
+	"data.SupplierEntry":          "SupplierEntry",
+	"main.templateInput":          "TemplateInput",
+	"main.templateEditInput":      "TemplateEditInput",
+	"data.Template":               "Template",
+	"main.bidEditInput":           "BidEditInput",
+	"main.tenderImportItem":       "TenderImportItem",
+}
+
+// schemaRules holds the validation rules behind request schemas, so that
+// the spec and the handlers share one declaration.
+var schemaRules = map[string]func() validator.FieldRules{
+	"TenderInput":     tenderInputRules,
+	"TenderEditInput": tenderEditInputRules,
+	"BidInput":        bidInputRules,
+	"BidEditInput":    bidEditInputRules,
+
+	"TenderImportItem": tenderImportItemRules,
+
+	"TemplateInput":     templateInputRules,
+	"TemplateEditInput": templateEditInputRules,
+
+	"ServiceTypeInput":     serviceTypeInputRules,
+	"ServiceTypeEditInput": serviceTypeEditInputRules,
+	"InvitationInput":      invitationInputRules,
+
+	"SupplierEntryInput":     supplierEntryInputRules,
+	"SupplierEntryEditInput": supplierEntryEditInputRules,
+}
+
+func applyRules(schema *openapi.Schema, rules validator.FieldRules) {
+	schema.Required = nil
+	for name, fieldRules := range rules {
+		property, ok := schema.Properties[name]
+		if !ok {
+			continue
+		}
+		for _, rule := range fieldRules {
+			switch rule.Kind {
+			case validator.KindRequired:
+				schema.Required = append(schema.Required, name)
+			case validator.KindMaxRunes:
+				property.MaxLength = intPtr(rule.Max)
+			case validator.KindOneOf:
+				property.Enum = rule.Values
+			case validator.KindUUID:
+				property.Format = "uuid"
+			case validator.KindRFC3339:
+				property.Format = "date-time"
+			}
+		}
+	}
+	sort.Strings(schema.Required)
+}
+
+// routeOperations lists the routes registered on the router as "METHOD /path".
+func routeOperations(router *mux.Router) []string {
+	var res []string
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			res = append(res, method+" "+path)
+		}
+		return nil
+	})
+	sort.Strings(res)
+	return res
+}
+
+func schemaFor(doc *openapi.Document, v any) *openapi.Schema {
+	switch v := v.(type) {
+	case []*data.Tender:
+		return openapi.ArrayOf(schemaFor(doc, data.Tender{}))
+	case []*data.Bid:
+		return openapi.ArrayOf(schemaFor(doc, data.Bid{}))
+	case []tenderInput:
+		return openapi.ArrayOf(schemaFor(doc, tenderInput{}))
+	case []tenderImportItem:
+		return openapi.ArrayOf(schemaFor(doc, tenderImportItem{}))
+	case envelope:
+		return &openapi.Schema{Type: "object"}
+	default:
+		name, ok := schemaNames[reflect.TypeOf(v).String()]
+		if !ok {
+			return openapi.SchemaOf(reflect.TypeOf(v))
+		}
+		ref := doc.Register(name, v)
+		if rules, ok := schemaRules[name]; ok {
+			applyRules(doc.Components.Schemas[name], rules())
+		}
+		return ref
+	}
+}
+
+func responseFor(doc *openapi.Document, v any) openapi.Response {
+	switch v.(type) {
+	case textResponse:
+		return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{"text/plain": {Schema: openapi.String()}}}
+	case htmlResponse:
+		return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{"text/html": {Schema: openapi.String()}}}
+	case reportResponse:
+		binary := &openapi.Schema{Type: "string", Format: "binary"}
+		return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{
+			"text/csv":                            {Schema: openapi.String()},
+			export.ContentType(export.FormatXLSX): {Schema: binary},
+		}}
+	case nil:
+		return openapi.Response{Description: "OK"}
+	default:
+		return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{"application/json": {Schema: schemaFor(doc, v)}}}
+	}
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '_' || r == '.' || r == '-' }) {
+		part = strings.Trim(part, "{}")
+		if part == "api" || part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func buildOpenAPI(router *mux.Router) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Tender management API",
+		Version:     "1.0.0",
+		Description: "Generated from the route table of the service.",
+	})
+
+	errorSchema := doc.Register("Problem", problem{})
+	problemContent := map[string]openapi.MediaType{"application/problem+json": {Schema: errorSchema}}
+
+	for _, operation := range routeOperations(router) {
+		method, path, _ := strings.Cut(operation, " ")
+		rd := routeDocs[operation]
+
+		op := &openapi.Operation{
+			OperationID: operationID(method, path),
+			Summary:     rd.summary,
+			Responses:   map[string]openapi.Response{"200": responseFor(doc, rd.response)},
+		}
+		if _, ok := rd.response.(noContentResponse); ok {
+			op.Responses = map[string]openapi.Response{"204": {Description: "No Content"}}
+		}
+		if rd.tag != "" {
+			op.Tags = []string{rd.tag}
+		}
+
+		for _, match := range pathParamRegexp.FindAllStringSubmatch(path, -1) {
+			schema := openapi.String()
+			if match[1] == "version" {
+				schema = &openapi.Schema{Type: "integer", Format: "int32", Minimum: intPtr(1)}
+			} else if strings.HasSuffix(match[1], "Id") {
+				schema = &openapi.Schema{Type: "string", Format: "uuid"}
+			}
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: match[1], In: "path", Required: true, Schema: schema})
+		}
+		for _, q := range rd.query {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: q.name, In: "query", Required: q.required, Description: q.description, Schema: q.schema})
+		}
+		if method == http.MethodPost {
+			op.Parameters = append(op.Parameters, openapi.Parameter{
+				Name:        idempotencyKeyHeader,
+				In:          "header",
+				Description: "retries with the same key get the stored response instead of running the request again",
+				Schema:      &openapi.Schema{Type: "string", MaxLength: intPtr(maxIdempotencyKey)},
+			})
+		}
+
+		if rd.body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{"application/json": {Schema: schemaFor(doc, rd.body)}},
+			}
+		}
+
+		for _, status := range rd.statuses {
+			op.Responses[strconv.Itoa(status)] = openapi.Response{
+				Description: http.StatusText(status),
+				Content:     problemContent,
+			}
+		}
+		if len(op.Parameters) > 0 || op.RequestBody != nil {
+			op.Responses["422"] = openapi.Response{Description: http.StatusText(http.StatusUnprocessableEntity), Content: problemContent}
+		}
+		if method == http.MethodPost {
+			op.Responses["409"] = openapi.Response{Description: http.StatusText(http.StatusConflict), Content: problemContent}
+		}
+		op.Responses["429"] = openapi.Response{Description: http.StatusText(http.StatusTooManyRequests), Content: problemContent}
+		op.Responses["500"] = openapi.Response{Description: http.StatusText(http.StatusInternalServerError), Content: problemContent}
+
+		doc.AddOperation(method, path, op)
+	}
+
+	return doc
+}
+
+func (app *application) openAPIHandler(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docsPage)
+}
+
+func mustMarshalSpec(doc *openapi.Document) []byte {
+	js, err := json.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	return js
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"avitotask/internal/config"
+	"avitotask/internal/ratelimit"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+
+	cfg := config.Default()
+	return &application{
+		config:  cfg,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		workers: newWorkerGroup(),
+		limiter: ratelimit.NewMemoryStore(cfg.RateLimit.MaxBuckets),
+	}
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	app := newTestApplication(t)
+	router := app.router()
+
+	routes := routeOperations(router)
+	if len(routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+
+	for _, route := range routes {
+		if _, ok := routeDocs[route]; !ok {
+			t.Errorf("route %q is registered but not documented in routeDocs", route)
+		}
+	}
+	for route := range routeDocs {
+		if !slices.Contains(routes, route) {
+			t.Errorf("route %q is documented in routeDocs but not registered", route)
+		}
+	}
+
+	spec := buildOpenAPI(router)
+	if got := spec.Operations(); !slices.Equal(got, routes) {
+		t.Errorf("spec operations differ from routes\nspec:   %v\nroutes: %v", got, routes)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewServer(app.routes())
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	var doc struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("got openapi version %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/tenders/new"]["post"]; !ok {
+		t.Error("POST /api/tenders/new is missing from the spec")
+	}
+	for _, name := range []string{"Tender", "Bid", "TenderInput", "BidInput"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s is missing from the spec", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"avitotask/internal/config"
+	"avitotask/internal/data"
+	"avitotask/internal/ratelimit"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// rateLimitScope carries the limit of a user- or organization-keyed route
+// to the handler, which charges it once it has resolved the caller.
+type rateLimitScope struct {
+	route string
+	limit config.RouteLimit
+}
+
+// take charges the bucket under key and sets the rate limit headers. It
+// answers with 429 itself and returns false when the bucket is empty.
+func (app *application) take(w http.ResponseWriter, r *http.Request, key string, limit config.RouteLimit) bool {
+	res, err := app.limiter.Take(r.Context(), key, ratelimit.Limit{RPS: limit.RPS, Burst: limit.Burst}, time.Now())
+	if err != nil {
+		app.logger.Error("rate limiter failed", slog.String("error", err.Error()))
+		return true
+	}
+
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.ResetAfter.Seconds()))))
+
+	if !res.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds()))))
+		app.rateLimitExceededResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// limitActor charges the route limit of a user- or organization-keyed route
+// to the caller resolved by the handler. Requests whose caller never gets
+// resolved are only limited by IP.
+func (app *application) limitActor(w http.ResponseWriter, r *http.Request, userId, organizationId string) bool {
+	scope := contextGetRateLimit(r)
+	if scope == nil {
+		return true
+	}
+
+	key := "user:" + userId
+	if scope.limit.KeyBy == "organization" && organizationId != "" {
+		key = "org:" + organizationId
+	}
+	if key == "user:" {
+		return true
+	}
+
+	return app.take(w, r, scope.route+"|"+key, scope.limit)
+}
+
+// requestUser resolves the user making the request and charges the rate
+// limit to them, answering with 401, 429 or 500 itself on failure.
+func (app *application) requestUser(w http.ResponseWriter, r *http.Request, username string) (string, bool) {
+	userId, err := app.models.Tenders.GetUserID(username)
+	if err != nil {
+		if errors.Is(err, data.ErrUsernameNotFound) {
+			app.unauthorizedResponse(w, r, err)
+			return "", false
+		}
+		app.serverErrorResponse(w, r, err)
+		return "", false
+	}
+
+	organizationId := ""
+	if scope := contextGetRateLimit(r); scope != nil && scope.limit.KeyBy == "organization" {
+		organizationId, err = app.models.Tenders.GetUserOrganization(userId)
+		if err != nil && !errors.Is(err, data.ErrOrganizationNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return "", false
+		}
+	}
+
+	if !app.limitActor(w, r, userId, organizationId) {
+		return "", false
+	}
+	return userId, true
+}
+
+func (app *application) clientIP(r *http.Request) string {
+	if app.config.RateLimit.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return realIP
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.config.RateLimit.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		route = r.Method + " " + route
+		if route == "GET /healthz" || route == "GET /readyz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The caller is only known after the handler has looked the user up,
+		// so until then every request is limited by IP.
+		limit := app.config.RateLimit.Limit(route)
+		if limit.KeyBy == "ip" {
+			if app.take(w, r, route+"|ip:"+app.clientIP(r), limit) {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
+
+		if !app.take(w, r, "ip:"+app.clientIP(r), app.config.RateLimit.PerIP) {
+			return
+		}
+		next.ServeHTTP(w, contextSetRateLimit(r, &rateLimitScope{route: route, limit: limit}))
+	})
+}
+
+func (app *application) startRateLimitCleanup() {
+	store, ok := app.limiter.(*ratelimit.MemoryStore)
+	if !ok || !app.config.RateLimit.Enabled {
+		return
+	}
+
+	app.background("ratelimit-cleanup", func(ctx context.Context) {
+		ticker := time.NewTicker(app.config.RateLimit.CleanupEvery.Std())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				store.Cleanup(now.Add(-app.config.RateLimit.IdleTimeout.Std()))
+			}
+		}
+	})
+}
@@ -6,25 +6,84 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func (app *application) routes() http.Handler {
+func (app *application) router() *mux.Router {
 	router := mux.NewRouter()
+	router.Use(app.rateLimit)
+	router.Use(app.idempotency)
 
 	router.HandleFunc("/api/ping", pingHandler).Methods("GET")
+	router.HandleFunc("/healthz", app.livenessHandler).Methods("GET")
+	router.HandleFunc("/readyz", app.readinessHandler).Methods("GET")
+	router.HandleFunc("/api/statuses", app.statusesHandler).Methods("GET")
+
+	router.HandleFunc("/api/service-types", app.getServiceTypesHandler).Methods("GET")
+	router.HandleFunc("/api/service-types/new", app.createServiceTypeHandler).Methods("POST")
+	router.HandleFunc("/api/service-types/{serviceType}", app.updateServiceTypeHandler).Methods("PATCH")
+	router.HandleFunc("/api/service-types/{serviceType}", app.deleteServiceTypeHandler).Methods("DELETE")
+
 	router.HandleFunc("/api/tenders", app.getTendersHandler).Methods("GET")
 	router.HandleFunc("/api/tenders/new", app.createNewTenderHandler).Methods("POST")
 	router.HandleFunc("/api/tenders/my", app.getMyTendersHandler).Methods("GET")
+	router.HandleFunc("/api/tenders/my/export", app.exportMyTendersHandler).Methods("GET")
+	router.HandleFunc("/api/tenders/bulk/new", app.bulkCreateTendersHandler).Methods("POST")
+	router.HandleFunc("/api/tenders/bulk/status", app.bulkTenderStatusHandler).Methods("POST")
+	router.HandleFunc("/api/tenders/import", app.importTendersHandler).Methods("POST")
+	router.HandleFunc("/api/tenders/{tenderId}", app.getTenderHandler).Methods("GET")
 	router.HandleFunc("/api/tenders/{tenderId}/status", app.getStatusHandler).Methods("GET")
 	router.HandleFunc("/api/tenders/{tenderId}/status", app.changeStatusHandler).Methods("PUT")
 	router.HandleFunc("/api/tenders/{tenderId}/edit", app.updateTenderHandler).Methods("PATCH")
 	router.HandleFunc("/api/tenders/{tenderId}/rollback/{version}", app.rollbackTenderHandler).Methods("PUT")
+	router.HandleFunc("/api/tenders/{tenderId}/clone", app.cloneTenderHandler).Methods("POST")
+	router.HandleFunc("/api/tenders/{tenderId}/visibility", app.changeVisibilityHandler).Methods("PUT")
+	router.HandleFunc("/api/tenders/{tenderId}/invitations", app.getTenderInvitationsHandler).Methods("GET")
+	router.HandleFunc("/api/tenders/{tenderId}/invitations", app.createInvitationHandler).Methods("POST")
+	router.HandleFunc("/api/tenders/{tenderId}/invitations/{invitationId}", app.deleteInvitationHandler).Methods("DELETE")
+	router.HandleFunc("/api/suppliers", app.getSupplierEntriesHandler).Methods("GET")
+	router.HandleFunc("/api/suppliers/new", app.createSupplierEntryHandler).Methods("POST")
+	router.HandleFunc("/api/suppliers/requirements", app.getQualificationRequirementsHandler).Methods("GET")
+	router.HandleFunc("/api/suppliers/requirements/{serviceType}", app.addQualificationRequirementHandler).Methods("PUT")
+	router.HandleFunc("/api/suppliers/requirements/{serviceType}", app.deleteQualificationRequirementHandler).Methods("DELETE")
+	router.HandleFunc("/api/suppliers/{entryId}", app.updateSupplierEntryHandler).Methods("PATCH")
+	router.HandleFunc("/api/suppliers/{entryId}", app.deleteSupplierEntryHandler).Methods("DELETE")
+	router.HandleFunc("/api/invitations/my", app.getMyInvitationsHandler).Methods("GET")
+	router.HandleFunc("/api/invitations/{invitationId}/accept", app.acceptInvitationHandler).Methods("PUT")
+
+	router.HandleFunc("/api/templates", app.getTemplatesHandler).Methods("GET")
+	router.HandleFunc("/api/templates/new", app.createTemplateHandler).Methods("POST")
+	router.HandleFunc("/api/templates/{templateId}", app.getTemplateHandler).Methods("GET")
+	router.HandleFunc("/api/templates/{templateId}", app.updateTemplateHandler).Methods("PATCH")
+	router.HandleFunc("/api/templates/{templateId}", app.deleteTemplateHandler).Methods("DELETE")
+	router.HandleFunc("/api/templates/{templateId}/createFromTemplate", app.createFromTemplateHandler).Methods("POST")
 
 	router.HandleFunc("/api/bids/new", app.createBidHandler).Methods("POST")
 	router.HandleFunc("/api/bids/my", app.getMyBidsHandler).Methods("GET")
+	router.HandleFunc("/api/bids/bulk/decision", app.bulkBidDecisionHandler).Methods("POST")
 	router.HandleFunc("/api/bids/{bidId}/status", app.changeBidStatusHandler).Methods("PUT")
+	router.HandleFunc("/api/bids/{bidId}", app.getBidHandler).Methods("GET")
 	router.HandleFunc("/api/bids/{bidId}/status", app.getBidStatusHandler).Methods("GET")
 	router.HandleFunc("/api/bids/{tenderId}/list", app.getBidsForTenderHandler).Methods("GET")
+	router.HandleFunc("/api/bids/{tenderId}/export", app.exportTenderBidsHandler).Methods("GET")
 	router.HandleFunc("/api/bids/{bidId}/edit", app.updateBidHandler).Methods("PATCH")
 	router.HandleFunc("/api/bids/{bidId}/rollback/{version}", app.rollbackBidHandler).Methods("PUT")
 	router.HandleFunc("/api/bids/{bidId}/submit_decision", app.submitDecisionHandler).Methods("PUT")
+	router.HandleFunc("/api/bids/{bidId}/withdraw", app.withdrawBidHandler).Methods("PUT")
+	router.HandleFunc("/api/bids/{bidId}/resubmit", app.resubmitBidHandler).Methods("PUT")
+	router.HandleFunc("/api/bids/{bidId}/approvals", app.getBidApprovalsHandler).Methods("GET")
+	router.HandleFunc("/api/bids/{bidId}/approvals/me", app.revokeApprovalHandler).Methods("DELETE")
+	router.HandleFunc("/api/bids/{bidId}/approvals/versions", app.bidApprovalVersionsHandler).Methods("GET")
+	router.HandleFunc("/api/decisions/pending", app.getPendingDecisionsHandler).Methods("GET")
+
+	router.HandleFunc("/api/docs", docsHandler).Methods("GET")
+	spec := router.Path("/api/openapi.json").Methods("GET")
+	spec.HandlerFunc(app.openAPIHandler(mustMarshalSpec(buildOpenAPI(router))))
+
 	return router
 }
+
+func (app *application) routes() http.Handler {
+	var handler http.Handler = app.recoverPanic(app.router())
+	if app.config.Features.AccessLog {
+		handler = app.logRequest(handler)
+	}
+	return app.requestID(handler)
+}
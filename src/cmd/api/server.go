@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func (app *application) serve() error {
+	srv := &http.Server{
+		Addr:           app.config.Addr,
+		Handler:        app.routes(),
+		ReadTimeout:    app.config.Server.ReadTimeout.Std(),
+		WriteTimeout:   app.config.Server.WriteTimeout.Std(),
+		IdleTimeout:    app.config.Server.IdleTimeout.Std(),
+		MaxHeaderBytes: app.config.Server.MaxHeaderBytes,
+		ErrorLog:       slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+	}
+
+	shutdownError := make(chan error)
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Info("shutting down server", slog.String("signal", s.String()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), app.config.Server.ShutdownTimeout.Std())
+		defer cancel()
+
+		// Workers are stopped even if draining connections timed out, so that
+		// they are always waited for.
+		err := srv.Shutdown(ctx)
+
+		app.logger.Info("stopping background workers")
+		shutdownError <- errors.Join(err, app.workers.stop(ctx))
+	}()
+
+	app.logger.Info("starting server", slog.String("addr", srv.Addr))
+
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("stopped server", slog.String("addr", srv.Addr))
+	return nil
+}
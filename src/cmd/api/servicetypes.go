@@ -0,0 +1,238 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/i18n"
+	"avitotask/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type serviceTypeInput struct {
+	Code       string            `json:"code"`
+	ParentCode string            `json:"parentCode,omitempty"`
+	Names      map[string]string `json:"names"`
+	Active     *bool             `json:"active,omitempty"`
+}
+
+type serviceTypeEditInput struct {
+	ParentCode *string           `json:"parentCode,omitempty"`
+	Names      map[string]string `json:"names,omitempty"`
+	Active     *bool             `json:"active,omitempty"`
+}
+
+func serviceTypeInputRules() validator.FieldRules {
+	return data.ServiceTypeRules()
+}
+
+func serviceTypeEditInputRules() validator.FieldRules {
+	return data.ServiceTypeRules().Optional()
+}
+
+// validateNames checks the localized names: only supported languages, and an
+// English name, which is the fallback, whenever names are given.
+func validateNames(v *validator.Validator, names map[string]string) {
+	languages := i18n.Languages()
+	v.Field("names."+i18n.Default, names[i18n.Default], validator.Required())
+	for lang, name := range names {
+		v.Field("names", lang, validator.OneOf(languages...))
+		v.Field("names."+lang, name, validator.MaxRunes(100))
+	}
+}
+
+func (input serviceTypeInput) validate(v *validator.Validator) {
+	v.Struct(input, serviceTypeInputRules())
+	validateNames(v, input.Names)
+}
+
+func (input serviceTypeEditInput) validate(v *validator.Validator) {
+	if input.ParentCode != nil {
+		v.Field("parentCode", *input.ParentCode, validator.MaxRunes(100))
+	}
+	if input.Names != nil {
+		validateNames(v, input.Names)
+	}
+}
+
+// localizeServiceType fills in the display name for lang, falling back to
+// English and then to the code.
+func localizeServiceType(serviceType *data.ServiceType, lang string) {
+	switch {
+	case serviceType.Names[lang] != "":
+		serviceType.Name = serviceType.Names[lang]
+	case serviceType.Names[i18n.Default] != "":
+		serviceType.Name = serviceType.Names[i18n.Default]
+	default:
+		serviceType.Name = serviceType.Code
+	}
+}
+
+func (app *application) getServiceTypesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	includeInactive := readQueryValue(q, "includeInactive", v)
+	v.Field("includeInactive", includeInactive, validator.OneOf("true", "false"))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	serviceTypes, err := app.models.ServiceTypes.GetServiceTypes(includeInactive == "true")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	lang := requestLanguage(r)
+	for _, serviceType := range serviceTypes {
+		localizeServiceType(serviceType, lang)
+	}
+
+	err = writeJSON(w, r, http.StatusOK, serviceTypes, http.Header{"Content-Language": {lang}})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) serviceTypeErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, data.ErrServiceTypeNotFound):
+		app.notFoundError(w, r, err)
+	case errors.Is(err, data.ErrServiceTypeExists), errors.Is(err, data.ErrServiceTypeInUse):
+		app.conflictResponse(w, r, err)
+	case errors.Is(err, data.ErrServiceTypeParent):
+		v := validator.New()
+		v.AddMessage("parentCode", "SERVICE_TYPE_PARENT_INVALID")
+		app.failedValidationResponse(w, r, v)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createServiceTypeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+
+	var input serviceTypeInput
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.requireAdmin(w, r, username) {
+		return
+	}
+
+	serviceType := data.ServiceType{
+		Code:       input.Code,
+		ParentCode: input.ParentCode,
+		Names:      input.Names,
+		Active:     input.Active == nil || *input.Active,
+	}
+
+	err = app.models.ServiceTypes.InsertServiceType(&serviceType)
+	if err != nil {
+		app.serviceTypeErrorResponse(w, r, err)
+		return
+	}
+
+	localizeServiceType(&serviceType, requestLanguage(r))
+
+	err = writeJSON(w, r, http.StatusOK, serviceType, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateServiceTypeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	code := mux.Vars(r)["serviceType"]
+
+	username := readUsernameQuery(q, v)
+
+	var input serviceTypeEditInput
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.requireAdmin(w, r, username) {
+		return
+	}
+
+	serviceType, err := app.models.ServiceTypes.GetServiceType(code)
+	if err != nil {
+		app.serviceTypeErrorResponse(w, r, err)
+		return
+	}
+
+	if input.ParentCode != nil {
+		serviceType.ParentCode = *input.ParentCode
+	}
+	if input.Names != nil {
+		serviceType.Names = input.Names
+	}
+	if input.Active != nil {
+		serviceType.Active = *input.Active
+	}
+
+	err = app.models.ServiceTypes.UpdateServiceType(serviceType)
+	if err != nil {
+		app.serviceTypeErrorResponse(w, r, err)
+		return
+	}
+
+	localizeServiceType(serviceType, requestLanguage(r))
+
+	err = writeJSON(w, r, http.StatusOK, serviceType, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteServiceTypeHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	code := mux.Vars(r)["serviceType"]
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	if !app.requireAdmin(w, r, username) {
+		return
+	}
+
+	err := app.models.ServiceTypes.DeleteServiceType(code)
+	if err != nil {
+		app.serviceTypeErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
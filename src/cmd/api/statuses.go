@@ -0,0 +1,30 @@
+package main
+
+import (
+	"avitotask/internal/i18n"
+	"net/http"
+)
+
+func statusNames(lang, kind string, statuses map[string]bool) map[string]string {
+	res := make(map[string]string, len(statuses))
+	for status := range statuses {
+		res[status] = i18n.T(lang, "status."+kind+"."+status)
+	}
+	return res
+}
+
+// statusesHandler returns display names of tender and bid statuses in the
+// language negotiated from Accept-Language.
+func (app *application) statusesHandler(w http.ResponseWriter, r *http.Request) {
+	lang := requestLanguage(r)
+
+	body := envelope{
+		"tender": statusNames(lang, "tender", availableStatuses),
+		"bid":    statusNames(lang, "bid", bidStatuses),
+	}
+
+	err := writeJSON(w, r, http.StatusOK, body, http.Header{"Content-Language": {lang}})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/validator"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type supplierEntryInput struct {
+	List        string `json:"list"`
+	SubjectType string `json:"subjectType"`
+	SubjectId   string `json:"subjectId"`
+	Reason      string `json:"reason"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+}
+
+type supplierEntryEditInput struct {
+	Reason    string `json:"reason,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+func supplierEntryInputRules() validator.FieldRules {
+	return data.SupplierEntryRules()
+}
+
+func supplierEntryEditInputRules() validator.FieldRules {
+	rules := data.SupplierEntryRules().Optional()
+	return validator.FieldRules{"reason": rules["reason"], "expiresAt": rules["expiresAt"]}
+}
+
+func (input supplierEntryInput) validate(v *validator.Validator) {
+	v.Struct(input, supplierEntryInputRules())
+}
+
+func (input supplierEntryEditInput) validate(v *validator.Validator) {
+	v.Struct(input, supplierEntryEditInputRules())
+}
+
+// checkSupplier enforces the allowlist, the blocklist and the qualification
+// requirements of the tender's organization, answering with the reason of a
+// rejection itself.
+func (app *application) checkSupplier(w http.ResponseWriter, r *http.Request, tender *data.Tender, bidderIds []string) bool {
+	check, err := app.models.Suppliers.CheckSupplier(tender.OrganizationId, tender.ServiceType, bidderIds)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	switch {
+	case check.Blocked:
+		app.forbiddenResponse(w, r, ErrSupplierBlocked)
+		return false
+	case check.Required && !check.Qualified && check.Expired:
+		app.forbiddenResponse(w, r, ErrQualificationExpired)
+		return false
+	case check.Required && !check.Qualified:
+		app.forbiddenResponse(w, r, ErrSupplierNotQualified)
+		return false
+	}
+	return true
+}
+
+// organizationSupplierEntry loads a list entry and checks that it belongs to
+// the organization.
+func (app *application) organizationSupplierEntry(w http.ResponseWriter, r *http.Request, entryId, organizationId string) bool {
+	entry, err := app.models.Suppliers.GetEntry(entryId)
+	if err != nil {
+		if errors.Is(err, data.ErrSupplierEntryNotFound) {
+			app.notFoundError(w, r, err)
+			return false
+		}
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if entry.OrganizationId != organizationId {
+		app.forbiddenResponse(w, r, data.ErrNoRights)
+		return false
+	}
+	return true
+}
+
+func (app *application) getSupplierEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
+	list := readQueryValue(q, "list", v)
+	v.Field("list", list, validator.OneOf(data.SupplierAllowList, data.SupplierBlockList))
+	includeExpired := readQueryValue(q, "includeExpired", v)
+	v.Field("includeExpired", includeExpired, validator.OneOf("true", "false"))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	entries, err := app.models.Suppliers.GetEntries(limit, offset, organizationId, list, includeExpired == "true")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, entries, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) createSupplierEntryHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+
+	var input supplierEntryInput
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	var exists bool
+	if input.SubjectType == "User" {
+		exists, err = app.models.Tenders.UserExists(input.SubjectId)
+	} else {
+		exists, err = app.models.Tenders.OrganizationExists(input.SubjectId)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !exists {
+		v.AddMessage("subjectId", CodeSupplierNotFound)
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	entry := data.SupplierEntry{
+		Id:             uuid.New().String(),
+		OrganizationId: organizationId,
+		List:           input.List,
+		SubjectType:    input.SubjectType,
+		SubjectId:      input.SubjectId,
+		Reason:         input.Reason,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+	}
+
+	err = app.models.Suppliers.InsertEntry(&entry)
+	if err != nil {
+		if errors.Is(err, data.ErrSupplierEntryExists) {
+			app.conflictResponse(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, entry, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateSupplierEntryHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	entryId := mux.Vars(r)["entryId"]
+	_, err := uuid.Parse(entryId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrSupplierEntryNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	var input supplierEntryEditInput
+	err = readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.validate(v); !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+	if !app.organizationSupplierEntry(w, r, entryId, organizationId) {
+		return
+	}
+
+	entry, err := app.models.Suppliers.UpdateEntry(entryId, input.Reason, input.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, data.ErrSupplierEntryNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, entry, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteSupplierEntryHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	entryId := mux.Vars(r)["entryId"]
+	_, err := uuid.Parse(entryId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrSupplierEntryNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+	if !app.organizationSupplierEntry(w, r, entryId, organizationId) {
+		return
+	}
+
+	err = app.models.Suppliers.DeleteEntry(entryId)
+	if err != nil {
+		if errors.Is(err, data.ErrSupplierEntryNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getQualificationRequirementsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	serviceTypes, err := app.models.Suppliers.GetRequirements(organizationId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, serviceTypes, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// addQualificationRequirementHandler makes the organization accept bids on
+// tenders of the service type, or of its subcategories, only from
+// allowlisted suppliers. Adding an existing requirement is a no-op.
+func (app *application) addQualificationRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	serviceType := mux.Vars(r)["serviceType"]
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	_, err := app.models.ServiceTypes.GetServiceType(serviceType)
+	if err != nil {
+		if errors.Is(err, data.ErrServiceTypeNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Suppliers.AddRequirement(organizationId, serviceType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) deleteQualificationRequirementHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	serviceType := mux.Vars(r)["serviceType"]
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	err := app.models.Suppliers.DeleteRequirement(organizationId, serviceType)
+	if err != nil {
+		if errors.Is(err, data.ErrRequirementNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/validator"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type templateInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ServiceType string `json:"serviceType"`
+}
+
+type templateEditInput struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	ServiceType string `json:"serviceType,omitempty"`
+}
+
+func templateInputRules() validator.FieldRules {
+	return data.TenderRules()
+}
+
+func templateEditInputRules() validator.FieldRules {
+	return data.TenderRules().Optional()
+}
+
+func (template templateInput) validate(v *validator.Validator) {
+	v.Struct(template, templateInputRules())
+}
+
+func (template templateEditInput) validate(v *validator.Validator) {
+	v.Struct(template, templateEditInputRules())
+}
+
+// userOrganization resolves the organization the user is responsible for,
+// answering with 401 or 403 itself when there is none.
+func (app *application) userOrganization(w http.ResponseWriter, r *http.Request, username string) (string, bool) {
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
+		return "", false
+	}
+
+	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
+	if err != nil {
+		if errors.Is(err, data.ErrOrganizationNotFound) {
+			app.forbiddenResponse(w, r, data.ErrNoRights)
+			return "", false
+		}
+		app.serverErrorResponse(w, r, err)
+		return "", false
+	}
+
+	return organizationId, true
+}
+
+// organizationTemplate loads a template and checks that it belongs to the
+// user's organization.
+func (app *application) organizationTemplate(w http.ResponseWriter, r *http.Request, templateId, organizationId string) (*data.Template, bool) {
+	template, err := app.models.Templates.GetTemplate(templateId)
+	if err != nil {
+		if errors.Is(err, data.ErrTemplateNotFound) {
+			app.notFoundError(w, r, err)
+			return nil, false
+		}
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if template.OrganizationId != organizationId {
+		app.forbiddenResponse(w, r, data.ErrNoRights)
+		return nil, false
+	}
+
+	return template, true
+}
+
+func readTemplateId(vars map[string]string) (string, bool) {
+	templateId := vars["templateId"]
+	_, err := uuid.Parse(templateId)
+	return templateId, err == nil
+}
+
+func (app *application) createTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	username := readUsernameQuery(q, v)
+
+	var input templateInput
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.validate(v)
+	if err := app.checkServiceType(v, "serviceType", input.ServiceType); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	template := data.Template{
+		Id:             uuid.New().String(),
+		Name:           input.Name,
+		Description:    input.Description,
+		ServiceType:    input.ServiceType,
+		OrganizationId: organizationId,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err = app.models.Templates.InsertTemplate(&template)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, template, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	templates, err := app.models.Templates.GetTemplates(limit, offset, organizationId)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, templates, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	templateId, ok := readTemplateId(mux.Vars(r))
+	if !ok {
+		app.notFoundError(w, r, data.ErrTemplateNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	template, ok := app.organizationTemplate(w, r, templateId, organizationId)
+	if !ok {
+		return
+	}
+
+	err := writeJSON(w, r, http.StatusOK, template, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	templateId, ok := readTemplateId(mux.Vars(r))
+	if !ok {
+		app.notFoundError(w, r, data.ErrTemplateNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	var input templateEditInput
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.validate(v)
+	if err := app.checkServiceType(v, "serviceType", input.ServiceType); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	if _, ok := app.organizationTemplate(w, r, templateId, organizationId); !ok {
+		return
+	}
+
+	template, err := app.models.Templates.UpdateTemplate(templateId, data.Template{
+		Name:        input.Name,
+		Description: input.Description,
+		ServiceType: input.ServiceType,
+	})
+	if err != nil {
+		if errors.Is(err, data.ErrTemplateNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = writeJSON(w, r, http.StatusOK, template, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	templateId, ok := readTemplateId(mux.Vars(r))
+	if !ok {
+		app.notFoundError(w, r, data.ErrTemplateNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	if _, ok := app.organizationTemplate(w, r, templateId, organizationId); !ok {
+		return
+	}
+
+	err := app.models.Templates.DeleteTemplate(templateId)
+	if err != nil {
+		if errors.Is(err, data.ErrTemplateNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createFromTemplateHandler creates a tender from a template of the user's
+// organization.
+func (app *application) createFromTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+
+	templateId, ok := readTemplateId(mux.Vars(r))
+	if !ok {
+		app.notFoundError(w, r, data.ErrTemplateNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+	deadline := readQueryValue(q, "deadline", v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	template, err := app.models.Templates.GetTemplate(templateId)
+	if err != nil {
+		if errors.Is(err, data.ErrTemplateNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Templates of other organizations are reported as missing, so that the
+	// endpoint does not reveal which ids exist.
+	if template.OrganizationId != organizationId {
+		app.notFoundError(w, r, data.ErrTemplateNotFound)
+		return
+	}
+
+	input := tenderInput{
+		Name:            template.Name,
+		Description:     template.Description,
+		ServiceType:     template.ServiceType,
+		OrganizationID:  organizationId,
+		CreatorUsername: username,
+		Deadline:        deadline,
+	}
+	if !app.validTenderInput(w, r, input) {
+		return
+	}
+
+	app.storeTender(w, r, input)
+}
@@ -2,22 +2,16 @@ package main
 
 import (
 	"avitotask/internal/data"
+	"avitotask/internal/validator"
 	"errors"
 	"net/http"
-	"strconv"
+	"net/url"
 	"time"
-	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
-var availableServices = map[string]bool{
-	"Construction": true,
-	"Delivery":     true,
-	"Manufacture":  true,
-}
-
 var availableStatuses = map[string]bool{
 	"Created":   true,
 	"Published": true,
@@ -30,87 +24,111 @@ type tenderInput struct {
 	ServiceType     string `json:"serviceType"`
 	OrganizationID  string `json:"organizationId"`
 	CreatorUsername string `json:"creatorUsername"`
+	Deadline        string `json:"deadline,omitempty"`
+	Visibility      string `json:"visibility,omitempty"`
 }
 
-func (tender tenderInput) validate() error {
-	if tender.Name == "" || tender.Description == "" || tender.ServiceType == "" || tender.OrganizationID == "" || tender.CreatorUsername == "" {
-		return errors.New("empty fields are not permitted")
-	}
-
-	if utf8.RuneCountInString(tender.Name) > 100 {
-		return errors.New("tenderName cannot be longer than 100 symbols")
-	}
-
-	if utf8.RuneCountInString(tender.Description) > 500 {
-		return errors.New("tenderDescription cannot be longer than 500 symbols")
-	}
-	if utf8.RuneCountInString(tender.OrganizationID) > 100 {
-		return errors.New("tenderOrganizationId cannot be longer than 100 symbols")
-	}
+type tenderEditInput struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	ServiceType string `json:"serviceType,omitempty"`
+}
 
-	if _, ok := availableServices[tender.ServiceType]; !ok {
-		return ErrWrongService(tender.ServiceType)
-	}
+func tenderInputRules() validator.FieldRules {
+	rules := data.TenderRules()
+	rules["organizationId"] = []validator.Rule{validator.Required(), validator.UUID()}
+	rules["creatorUsername"] = []validator.Rule{validator.Required()}
+	rules["deadline"] = []validator.Rule{validator.RFC3339()}
+	rules["visibility"] = []validator.Rule{validator.OneOf(data.Visibilities...)}
+	return rules
+}
 
-	return nil
+func tenderEditInputRules() validator.FieldRules {
+	return data.TenderRules().Optional()
 }
 
-func tryGetIntQuery(value []string) (int, error) {
-	if len(value) != 1 {
-		return 0, errors.New("query value must contain only one integer value")
-	}
-	res, err := strconv.Atoi(value[0])
+func (tender tenderInput) validate(v *validator.Validator) {
+	v.Struct(tender, tenderInputRules())
+}
 
-	if err != nil {
-		return 0, err
+// tender builds a new tender in status Created from validated input.
+func (input tenderInput) tender() data.Tender {
+	tender := data.Tender{
+		Id:             uuid.New().String(),
+		Name:           input.Name,
+		Description:    input.Description,
+		Status:         "Created",
+		ServiceType:    input.ServiceType,
+		OrganizationId: input.OrganizationID,
+		Version:        1,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+		Deadline:       input.Deadline,
+		Visibility:     input.Visibility,
 	}
-
-	if res < 1 {
-		return 0, errors.New("query integer value must be positive")
+	if tender.Visibility == "" {
+		tender.Visibility = data.VisibilityPublic
 	}
+	return tender
+}
 
-	return res, nil
+func (tender tenderEditInput) validate(v *validator.Validator) {
+	v.Struct(tender, tenderEditInputRules())
 }
 
-func tryGetUsernameQuery(value []string) (string, error) {
-	if len(value) != 1 {
-		return "", errors.New("username value must contain only one string value")
+// readServiceTypesQuery reads any number of service_type filters and checks
+// that each of them is in the catalogue.
+func (app *application) readServiceTypesQuery(qs url.Values, v *validator.Validator) ([]string, error) {
+	values := qs["service_type"]
+	if len(values) == 0 {
+		return values, nil
 	}
-	res := string(value[0])
 
-	if res == "" {
-		return "", errors.New("username must not be blank")
+	unknown, err := app.models.ServiceTypes.UnknownServiceTypes(values)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		v.AddMessage("service_type", "validation.unknownService", unknown[0])
 	}
 
-	return res, nil
+	return values, nil
 }
 
-func validateServiceTypeQuery(value []string) error {
-	if len(value) < 1 && len(value) > 3 {
-		return errors.New("the amount of service types must be between 1 and 3")
+// checkServiceType records a validation error under key unless code is an
+// active entry of the service type catalogue.
+func (app *application) checkServiceType(v *validator.Validator, key, code string) error {
+	if code == "" {
+		return nil
 	}
 
-	for _, s := range value {
-		if _, ok := availableServices[s]; !ok {
-			return ErrWrongService(s)
+	serviceType, err := app.models.ServiceTypes.GetServiceType(code)
+	if err != nil {
+		if errors.Is(err, data.ErrServiceTypeNotFound) {
+			v.AddMessage(key, "validation.unknownService", code)
+			return nil
 		}
+		return err
+	}
+	if !serviceType.Active {
+		v.AddMessage(key, "validation.inactiveService", code)
 	}
-
 	return nil
 }
 
-func tryGetStatusQuery(value []string) (string, error) {
-	if len(value) != 1 {
-		return "", errors.New("there can only be 1 status in request")
-	}
+func readStatusQuery(qs url.Values, statuses map[string]bool, v *validator.Validator) string {
+	status := readQueryValue(qs, "status", v)
+	checkStatus(v, "status", status, statuses)
+	return status
+}
 
-	for _, s := range value {
-		if _, ok := availableStatuses[s]; !ok {
-			return "", ErrWrongStatus(s)
-		}
+func checkStatus(v *validator.Validator, key, status string, statuses map[string]bool) {
+	if status == "" {
+		v.AddMessage(key, "validation.required")
+		return
+	}
+	if _, ok := statuses[status]; !ok {
+		v.AddMessage(key, "validation.unknownStatus", status)
 	}
-
-	return string(value[0]), nil
 }
 
 func (app *application) createNewTenderHandler(w http.ResponseWriter, r *http.Request) {
@@ -119,229 +137,172 @@ func (app *application) createNewTenderHandler(w http.ResponseWriter, r *http.Re
 	err := readJSON(w, r, &tenderInput)
 
 	if err != nil {
-		badRequestResponse(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	err = tenderInput.validate()
-
-	if err != nil {
-		badRequestResponse(w, r, err)
-		return
-	}
-
-	userId, err := app.models.Tenders.GetUserID(tenderInput.CreatorUsername)
+	app.createTender(w, r, tenderInput)
+}
 
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+// createTender validates the input, checks that the creator is responsible
+// for the organization and stores a new tender in status Created.
+func (app *application) createTender(w http.ResponseWriter, r *http.Request, tenderInput tenderInput) {
+	if !app.validTenderInput(w, r, tenderInput) {
 		return
 	}
 
-	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
-
-	if err != nil {
-		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, data.ErrNoRights)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	organizationId, ok := app.userOrganization(w, r, tenderInput.CreatorUsername)
+	if !ok {
 		return
 	}
 
 	if organizationId != tenderInput.OrganizationID {
-		forbiddenResponse(w, r, data.ErrNoRights)
+		app.forbiddenResponse(w, r, data.ErrNoRights)
 		return
 	}
 
-	tenderOutput := data.Tender{
-		Id:             uuid.New().String(),
-		Name:           tenderInput.Name,
-		Description:    tenderInput.Description,
-		Status:         "Created",
-		ServiceType:    tenderInput.ServiceType,
-		OrganizationId: tenderInput.OrganizationID,
-		Version:        1,
-		CreatedAt:      time.Now().Format(time.RFC3339),
+	app.storeTender(w, r, tenderInput)
+}
+
+// validTenderInput checks a new tender, answering with 422 itself when it is
+// invalid.
+func (app *application) validTenderInput(w http.ResponseWriter, r *http.Request, tenderInput tenderInput) bool {
+	v := validator.New()
+
+	tenderInput.validate(v)
+	if err := app.checkServiceType(v, "serviceType", tenderInput.ServiceType); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
 	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return false
+	}
+	return true
+}
 
-	err = app.models.Tenders.InsertTender(&tenderOutput)
+// storeTender inserts a tender whose creator has already been checked and
+// writes it back. Clones and tenders made from templates end up here too.
+func (app *application) storeTender(w http.ResponseWriter, r *http.Request, tenderInput tenderInput) {
+	tenderOutput := tenderInput.tender()
+
+	err := app.models.Tenders.InsertTender(&tenderOutput)
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, tenderOutput, nil)
-
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
-
 }
 
 func (app *application) getTendersHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		limit       int32
-		offset      int32
-		serviceType []string
-	}{
-		limit:       5,
-		offset:      0,
-		serviceType: []string{"", "", ""},
-	}
-	limit, found := q["limit"]
-	if found {
-		parsedLimit, err := tryGetIntQuery(limit)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.limit = int32(parsedLimit)
+	v := validator.New()
+
+	limit, offset := app.readPagination(q, v)
+	serviceTypes, err := app.readServiceTypesQuery(q, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
+	username := readQueryValue(q, "username", v)
 
-	offset, found := q["offset"]
-	if found {
-		parsedOffset, err := tryGetIntQuery(offset)
-		if err != nil {
-			badRequestResponse(w, r, err)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	// Anonymous callers only see tenders that are not private.
+	viewerIds := []string{}
+	if username != "" {
+		userId, ok := app.requestUser(w, r, username)
+		if !ok {
 			return
 		}
-		params.offset = int32(parsedOffset)
-	}
-	serviceType, found := q["service_type"]
-	if found {
-		err := validateServiceTypeQuery(serviceType)
+		viewerIds, err = app.viewerIds(userId)
 		if err != nil {
-			badRequestResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
-		for i, v := range serviceType {
-			params.serviceType[i] = v
-		}
 	}
 
-	tenders, err := app.models.Tenders.GetTenders(params.limit, params.offset, params.serviceType)
+	tenders, err := app.models.Tenders.GetTenders(limit, offset, serviceTypes, viewerIds)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, tenders, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
 
 func (app *application) getMyTendersHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
-	params := struct {
-		limit    int32
-		offset   int32
-		username string
-	}{
-		limit: 5,
-	}
-	limit, found := q["limit"]
-	if found {
-		parsedLimit, err := tryGetIntQuery(limit)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.limit = int32(parsedLimit)
-	}
-	offset, found := q["offset"]
-	if found {
-		parsedOffset, err := tryGetIntQuery(offset)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.offset = int32(parsedOffset)
-	}
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
+	v := validator.New()
 
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	limit, offset := app.readPagination(q, v)
+	username := readUsernameQuery(q, v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	organizationId, err := app.models.Tenders.GetUserOrganization(userId)
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	tenders, err := app.models.Tenders.GetMyTenders(params.limit, params.offset, organizationId)
+	tenders, err := app.models.Tenders.GetMyTenders(limit, offset, organizationId)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, tenders, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
 
 func (app *application) getStatusHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	tenderId := vars["tenderId"]
 
 	_, err := uuid.Parse(tenderId)
 
 	if err != nil {
-		notFoundError(w, r, data.ErrTenderNotFound)
+		app.notFoundError(w, r, data.ErrTenderNotFound)
 		return
 	}
 
-	params := struct {
-		username string
-	}{}
-
-	username, found := q["username"]
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
+	username := readUsernameQuery(q, v)
 
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -349,34 +310,29 @@ func (app *application) getStatusHandler(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if userOrganizationId != tenderOrganizationId {
-		forbiddenResponse(w, r, data.ErrNoRights)
+		app.forbiddenResponse(w, r, data.ErrNoRights)
 		return
 	}
 
@@ -384,10 +340,10 @@ func (app *application) getStatusHandler(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		} else {
-			serverErrorResponse(w, r, err)
+			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
@@ -398,44 +354,23 @@ func (app *application) getStatusHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) changeStatusHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		status   string
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	tenderId := vars["tenderId"]
 	_, err := uuid.Parse(tenderId)
 
 	if err != nil {
-		notFoundError(w, r, data.ErrTenderNotFound)
+		app.notFoundError(w, r, data.ErrTenderNotFound)
 		return
 	}
 
-	status, found := q["status"]
-	if found {
-		parsedStatus, err := tryGetStatusQuery(status)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.status = parsedStatus
-	} else {
-		badRequestResponse(w, r, errors.New("status must be provided"))
-		return
-	}
+	status := readStatusQuery(q, availableStatuses, v)
 
-	username, found := q["username"]
+	username := readUsernameQuery(q, v)
 
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -443,77 +378,63 @@ func (app *application) changeStatusHandler(w http.ResponseWriter, r *http.Reque
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if userOrganizationId != tenderOrganizationId {
-		forbiddenResponse(w, r, data.ErrNoRights)
+		app.forbiddenResponse(w, r, data.ErrNoRights)
 		return
 	}
 
-	tender, err := app.models.Tenders.ChangeTenderStatus(tenderId, params.status)
+	tender, err := app.models.Tenders.ChangeTenderStatus(tenderId, status)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, tender, nil)
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 }
 
 func (app *application) updateTenderHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	tenderId := vars["tenderId"]
 	_, err := uuid.Parse(tenderId)
 
 	if err != nil {
-		notFoundError(w, r, data.ErrTenderNotFound)
+		app.notFoundError(w, r, data.ErrTenderNotFound)
 		return
 	}
 
-	username, found := q["username"]
+	username := readUsernameQuery(q, v)
 
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -521,96 +442,86 @@ func (app *application) updateTenderHandler(w http.ResponseWriter, r *http.Reque
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if userOrganizationId != tenderOrganizationId {
-		forbiddenResponse(w, r, data.ErrNoRights)
+		app.forbiddenResponse(w, r, data.ErrNoRights)
 		return
 	}
 
-	tenderChanges := data.Tender{}
-	err = readJSON(w, r, &tenderChanges)
+	var input tenderEditInput
+	err = readJSON(w, r, &input)
 	if err != nil {
-		badRequestResponse(w, r, err)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	if _, ok := availableServices[tenderChanges.ServiceType]; len(tenderChanges.Name) > 100 || len(tenderChanges.Description) > 500 ||
-		(!ok && tenderChanges.ServiceType != "") {
-		badRequestResponse(w, r, errors.New("invalid parameters"))
+	input.validate(v)
+	if err := app.checkServiceType(v, "serviceType", input.ServiceType); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
+	tenderChanges := data.Tender{
+		Name:        input.Name,
+		Description: input.Description,
+		ServiceType: input.ServiceType,
+	}
+
 	newTender, err := app.models.Tenders.UpdateTender(tenderId, tenderChanges)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	err = writeJSON(w, r, http.StatusOK, newTender, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 }
 
 func (app *application) rollbackTenderHandler(w http.ResponseWriter, r *http.Request) {
-	params := struct {
-		username string
-	}{}
 	q := r.URL.Query()
+	v := validator.New()
 	vars := mux.Vars(r)
 	tenderId := vars["tenderId"]
 	_, err := uuid.Parse(tenderId)
 	if err != nil {
-		notFoundError(w, r, data.ErrTenderNotFound)
+		app.notFoundError(w, r, data.ErrTenderNotFound)
 		return
 	}
-	version, err := strconv.Atoi(vars["version"])
+	version := readVersionParam(vars, v)
+	username := readUsernameQuery(q, v)
 
-	if err != nil || version < 1 {
-		badRequestResponse(w, r, errors.New("version can only be an integer greater than 0"))
-		return
-	}
-
-	username, found := q["username"]
-
-	if found {
-		parsedUsername, err := tryGetUsernameQuery(username)
-		if err != nil {
-			badRequestResponse(w, r, err)
-			return
-		}
-		params.username = parsedUsername
-	} else {
-		badRequestResponse(w, r, errors.New("username must be provided"))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
@@ -618,34 +529,29 @@ func (app *application) rollbackTenderHandler(w http.ResponseWriter, r *http.Req
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	userId, err := app.models.Tenders.GetUserID(params.username)
-	if err != nil {
-		if errors.Is(err, data.ErrUsernameNotFound) {
-			unauthorizedResponse(w, r, err)
-			return
-		}
-		serverErrorResponse(w, r, err)
+	userId, ok := app.requestUser(w, r, username)
+	if !ok {
 		return
 	}
 	userOrganizationId, err := app.models.Tenders.GetUserOrganization(userId)
 	if err != nil {
 		if errors.Is(err, data.ErrOrganizationNotFound) {
-			forbiddenResponse(w, r, err)
+			app.forbiddenResponse(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
 	if userOrganizationId != tenderOrganizationId {
-		forbiddenResponse(w, r, data.ErrNoRights)
+		app.forbiddenResponse(w, r, data.ErrNoRights)
 		return
 	}
 
@@ -653,17 +559,167 @@ func (app *application) rollbackTenderHandler(w http.ResponseWriter, r *http.Req
 
 	if err != nil {
 		if errors.Is(err, data.ErrTenderVersionNotFound) {
-			notFoundError(w, r, err)
+			app.notFoundError(w, r, err)
 			return
 		}
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 	err = writeJSON(w, r, http.StatusOK, tender, nil)
 
 	if err != nil {
-		serverErrorResponse(w, r, err)
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+}
+
+// cloneTenderHandler creates a new tender in status Created from the current
+// or an earlier version of a tender of the user's organization.
+func (app *application) cloneTenderHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	vars := mux.Vars(r)
+	tenderId := vars["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	username := readUsernameQuery(q, v)
+	version := readIntQuery(q, "version", 0, 1, v)
+	deadline := readQueryValue(q, "deadline", v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
 		return
 	}
 
+	organizationId, ok := app.userOrganization(w, r, username)
+	if !ok {
+		return
+	}
+
+	// A tender of another organization is reported as missing, so that the
+	// endpoint does not reveal which ids exist.
+	sourceOrganizationId, err := app.models.Tenders.GetTenderOrganization(tenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if sourceOrganizationId != organizationId {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	source, err := app.models.Tenders.GetTenderVersion(tenderId, version)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) || errors.Is(err, data.ErrTenderVersionNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	clone := tenderInput{
+		Name:            source.Name,
+		Description:     source.Description,
+		ServiceType:     source.ServiceType,
+		OrganizationID:  organizationId,
+		CreatorUsername: username,
+		Deadline:        deadline,
+		Visibility:      source.Visibility,
+	}
+	if !app.validTenderInput(w, r, clone) {
+		return
+	}
+
+	app.storeTender(w, r, clone)
+}
+
+// tenderDetail is a tender as seen by one viewer: owners also get the bid
+// statistics.
+type tenderDetail struct {
+	data.Tender
+	Stats *data.TenderBidStats `json:"stats,omitempty"`
+}
+
+// getTenderHandler shows a single tender. Anyone may see a published public
+// or invite-only tender; private tenders are shown only to invitees, and
+// unpublished ones only to the tender's organization. Tenders hidden from
+// the viewer are reported as not found.
+func (app *application) getTenderHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	v := validator.New()
+	tenderId := mux.Vars(r)["tenderId"]
+	_, err := uuid.Parse(tenderId)
+	if err != nil {
+		app.notFoundError(w, r, data.ErrTenderNotFound)
+		return
+	}
+
+	username := readQueryValue(q, "username", v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v)
+		return
+	}
+
+	tender, err := app.models.Tenders.GetTenderById(tenderId)
+	if err != nil {
+		if errors.Is(err, data.ErrTenderNotFound) {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var viewerIds []string
+	if username != "" {
+		userId, ok := app.requestUser(w, r, username)
+		if !ok {
+			return
+		}
+		viewerIds, err = app.viewerIds(userId)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	detail := tenderDetail{Tender: *tender}
+
+	if containsString(viewerIds, tender.OrganizationId) {
+		detail.Stats, err = app.models.Bids.GetTenderBidStats(tenderId)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		visible := tender.Status == "Published"
+		if visible && tender.Visibility == data.VisibilityPrivate {
+			visible, err = app.models.Invitations.IsInvited(tenderId, viewerIds, false)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+		if !visible {
+			app.notFoundError(w, r, data.ErrTenderNotFound)
+			return
+		}
+	}
+
+	err = writeJSON(w, r, http.StatusOK, detail, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 }
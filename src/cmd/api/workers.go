@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type workerStatus struct {
+	Status    string    `json:"status"`
+	StartedAt time.Time `json:"startedAt"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	statuses map[string]*workerStatus
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel, statuses: map[string]*workerStatus{}}
+}
+
+func (g *workerGroup) setStatus(name, status, errMessage string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s, ok := g.statuses[name]
+	if !ok {
+		s = &workerStatus{StartedAt: time.Now()}
+		g.statuses[name] = s
+	}
+	s.Status = status
+	s.Error = errMessage
+}
+
+func (g *workerGroup) snapshot() map[string]workerStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	res := make(map[string]workerStatus, len(g.statuses))
+	for name, s := range g.statuses {
+		res[name] = *s
+	}
+	return res
+}
+
+func (app *application) background(name string, fn func(ctx context.Context)) {
+	app.workers.wg.Add(1)
+	app.workers.setStatus(name, "running", "")
+
+	go func() {
+		defer app.workers.wg.Done()
+		defer func() {
+			if p := recover(); p != nil {
+				app.logger.Error("background worker panicked", slog.String("worker", name), slog.String("error", fmt.Sprint(p)))
+				app.workers.setStatus(name, "failed", fmt.Sprint(p))
+				return
+			}
+			app.workers.setStatus(name, "stopped", "")
+		}()
+
+		fn(app.workers.ctx)
+	}()
+}
+
+func (g *workerGroup) stop(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,506 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var seconds float64
+		if err := json.Unmarshal(b, &seconds); err != nil {
+			return errors.New("duration must be a string like \"10s\" or a number of seconds")
+		}
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+type ServerConfig struct {
+	ReadTimeout     Duration `json:"readTimeout"`
+	WriteTimeout    Duration `json:"writeTimeout"`
+	IdleTimeout     Duration `json:"idleTimeout"`
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	MaxHeaderBytes  int      `json:"maxHeaderBytes"`
+}
+
+type DBConfig struct {
+	DSN             string   `json:"dsn"`
+	Host            string   `json:"host"`
+	Port            string   `json:"port"`
+	Username        string   `json:"username"`
+	Password        string   `json:"password"`
+	Database        string   `json:"database"`
+	SSLMode         string   `json:"sslMode"`
+	MaxOpenConns    int      `json:"maxOpenConns"`
+	MaxIdleConns    int      `json:"maxIdleConns"`
+	ConnMaxIdleTime Duration `json:"connMaxIdleTime"`
+	ConnectTimeout  Duration `json:"connectTimeout"`
+}
+
+type ApprovalConfig struct {
+	Quorum int `json:"quorum"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int `json:"defaultLimit"`
+	MaxLimit     int `json:"maxLimit"`
+}
+
+type FeaturesConfig struct {
+	AccessLog bool `json:"accessLog"`
+}
+
+// AdminConfig lists the users allowed to manage reference data such as the
+// service type catalogue.
+type AdminConfig struct {
+	Usernames []string `json:"usernames"`
+}
+
+// IdempotencyConfig sets how long responses to requests with an
+// Idempotency-Key header are kept for replay.
+type IdempotencyConfig struct {
+	TTL          Duration `json:"ttl"`
+	CleanupEvery Duration `json:"cleanupEvery"`
+}
+
+type RouteLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+	KeyBy string  `json:"keyBy"`
+}
+
+type RateLimitConfig struct {
+	Enabled    bool `json:"enabled"`
+	TrustProxy bool `json:"trustProxy"`
+	// PerIP limits every request by client IP before the handler resolves
+	// the user, on routes keyed by user or organization.
+	PerIP        RouteLimit            `json:"perIp"`
+	Default      RouteLimit            `json:"default"`
+	Routes       map[string]RouteLimit `json:"routes"`
+	MaxBuckets   int                   `json:"maxBuckets"`
+	IdleTimeout  Duration              `json:"idleTimeout"`
+	CleanupEvery Duration              `json:"cleanupEvery"`
+}
+
+// Limit returns the limit for a route given as "METHOD /path/template".
+func (c RateLimitConfig) Limit(route string) RouteLimit {
+	if l, ok := c.Routes[route]; ok {
+		if l.KeyBy == "" {
+			l.KeyBy = c.Default.KeyBy
+		}
+		return l
+	}
+	return c.Default
+}
+
+type Config struct {
+	Addr        string            `json:"addr"`
+	LogLevel    string            `json:"logLevel"`
+	Server      ServerConfig      `json:"server"`
+	DB          DBConfig          `json:"db"`
+	Approval    ApprovalConfig    `json:"approval"`
+	Pagination  PaginationConfig  `json:"pagination"`
+	Features    FeaturesConfig    `json:"features"`
+	RateLimit   RateLimitConfig   `json:"rateLimit"`
+	Admin       AdminConfig       `json:"admin"`
+	Idempotency IdempotencyConfig `json:"idempotency"`
+}
+
+func Default() Config {
+	return Config{
+		Addr:     ":8080",
+		LogLevel: "info",
+		Server: ServerConfig{
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(time.Minute),
+			ShutdownTimeout: Duration(20 * time.Second),
+			MaxHeaderBytes:  1 << 20,
+		},
+		DB: DBConfig{
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxIdleTime: Duration(15 * time.Minute),
+			ConnectTimeout:  Duration(10 * time.Second),
+		},
+		Approval: ApprovalConfig{
+			Quorum: 3,
+		},
+		Pagination: PaginationConfig{
+			DefaultLimit: 5,
+			MaxLimit:     100,
+		},
+		Features: FeaturesConfig{
+			AccessLog: true,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			PerIP:   RouteLimit{RPS: 50, Burst: 100, KeyBy: "ip"},
+			Default: RouteLimit{RPS: 10, Burst: 20, KeyBy: "user"},
+			Routes: map[string]RouteLimit{
+				"POST /api/bids/new": {RPS: 1, Burst: 5, KeyBy: "organization"},
+				"GET /api/tenders":   {RPS: 5, Burst: 10, KeyBy: "ip"},
+			},
+			MaxBuckets:   100000,
+			IdleTimeout:  Duration(10 * time.Minute),
+			CleanupEvery: Duration(time.Minute),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:          Duration(24 * time.Hour),
+			CleanupEvery: Duration(10 * time.Minute),
+		},
+	}
+}
+
+type Options struct {
+	File        string
+	PrintConfig bool
+}
+
+// Load builds the configuration from defaults, an optional JSON or YAML
+// file, environment variables and command-line flags, in that order.
+func Load(args []string, getenv func(string) string) (Config, Options, error) {
+	cfg := Default()
+	opts := Options{}
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", getenv("CONFIG_FILE"), "path to a JSON or YAML config file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective config with secrets redacted and exit")
+
+	flagAddr := fs.String("addr", "", "HTTP listen address")
+	flagDSN := fs.String("db-dsn", "", "PostgreSQL DSN")
+	flagMaxOpen := fs.Int("db-max-open-conns", 0, "PostgreSQL max open connections")
+	flagMaxIdle := fs.Int("db-max-idle-conns", 0, "PostgreSQL max idle connections")
+	flagQuorum := fs.Int("approval-quorum", 0, "approvals needed to close a tender")
+	flagLimit := fs.Int("page-default-limit", 0, "default page size")
+	flagLogLevel := fs.String("log-level", "", "log level (debug, info, warn, error)")
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, opts, err
+	}
+
+	if opts.File != "" {
+		if err := loadFile(opts.File, &cfg); err != nil {
+			return cfg, opts, fmt.Errorf("config file %s: %w", opts.File, err)
+		}
+	}
+
+	if err := applyEnv(&cfg, getenv); err != nil {
+		return cfg, opts, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *flagAddr
+		case "db-dsn":
+			cfg.DB.DSN = *flagDSN
+		case "db-max-open-conns":
+			cfg.DB.MaxOpenConns = *flagMaxOpen
+		case "db-max-idle-conns":
+			cfg.DB.MaxIdleConns = *flagMaxIdle
+		case "approval-quorum":
+			cfg.Approval.Quorum = *flagQuorum
+		case "page-default-limit":
+			cfg.Pagination.DefaultLimit = *flagLimit
+		case "log-level":
+			cfg.LogLevel = *flagLogLevel
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, opts, err
+	}
+
+	return cfg, opts, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		tree, err := parseYAML(content)
+		if err != nil {
+			return err
+		}
+		content, err = json.Marshal(tree)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported extension, use .json, .yaml or .yml")
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(content)))
+	dec.DisallowUnknownFields()
+	return dec.Decode(cfg)
+}
+
+func applyEnv(cfg *Config, getenv func(string) string) error {
+	var errs []error
+
+	str := func(key string, dst *string) {
+		if v := getenv(key); v != "" {
+			*dst = v
+		}
+	}
+	integer := func(key string, dst *int) {
+		if v := getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be an integer", key))
+				return
+			}
+			*dst = n
+		}
+	}
+	duration := func(key string, dst *Duration) {
+		if v := getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a duration like 10s", key))
+				return
+			}
+			*dst = Duration(d)
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v := getenv(key); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be a boolean", key))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	str("SERVER_ADDRESS", &cfg.Addr)
+	str("LOG_LEVEL", &cfg.LogLevel)
+
+	duration("SERVER_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	duration("SERVER_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	duration("SERVER_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	duration("SERVER_SHUTDOWN_TIMEOUT", &cfg.Server.ShutdownTimeout)
+	integer("SERVER_MAX_HEADER_BYTES", &cfg.Server.MaxHeaderBytes)
+
+	str("POSTGRES_CONN", &cfg.DB.DSN)
+	str("POSTGRES_HOST", &cfg.DB.Host)
+	str("POSTGRES_PORT", &cfg.DB.Port)
+	str("POSTGRES_USERNAME", &cfg.DB.Username)
+	str("POSTGRES_PASSWORD", &cfg.DB.Password)
+	str("POSTGRES_DATABASE", &cfg.DB.Database)
+	str("POSTGRES_SSLMODE", &cfg.DB.SSLMode)
+	integer("POSTGRES_MAX_OPEN_CONNS", &cfg.DB.MaxOpenConns)
+	integer("POSTGRES_MAX_IDLE_CONNS", &cfg.DB.MaxIdleConns)
+	duration("POSTGRES_CONN_MAX_IDLE_TIME", &cfg.DB.ConnMaxIdleTime)
+	duration("POSTGRES_CONNECT_TIMEOUT", &cfg.DB.ConnectTimeout)
+
+	integer("APPROVAL_QUORUM", &cfg.Approval.Quorum)
+	integer("PAGE_DEFAULT_LIMIT", &cfg.Pagination.DefaultLimit)
+	integer("PAGE_MAX_LIMIT", &cfg.Pagination.MaxLimit)
+
+	boolean("FEATURE_ACCESS_LOG", &cfg.Features.AccessLog)
+
+	boolean("RATE_LIMIT_ENABLED", &cfg.RateLimit.Enabled)
+	boolean("RATE_LIMIT_TRUST_PROXY", &cfg.RateLimit.TrustProxy)
+	if v := getenv("RATE_LIMIT_RPS"); v != "" {
+		rps, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			errs = append(errs, errors.New("RATE_LIMIT_RPS must be a number"))
+		} else {
+			cfg.RateLimit.Default.RPS = rps
+		}
+	}
+	integer("RATE_LIMIT_BURST", &cfg.RateLimit.Default.Burst)
+	integer("RATE_LIMIT_MAX_BUCKETS", &cfg.RateLimit.MaxBuckets)
+
+	duration("IDEMPOTENCY_TTL", &cfg.Idempotency.TTL)
+
+	if v := getenv("ADMIN_USERNAMES"); v != "" {
+		cfg.Admin.Usernames = nil
+		for _, username := range strings.Split(v, ",") {
+			if username = strings.TrimSpace(username); username != "" {
+				cfg.Admin.Usernames = append(cfg.Admin.Usernames, username)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if _, _, err := net.SplitHostPort(cfg.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("addr: %w", err))
+	}
+
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("logLevel: unknown level %q", cfg.LogLevel))
+	}
+
+	if cfg.Server.ReadTimeout <= 0 || cfg.Server.WriteTimeout <= 0 || cfg.Server.IdleTimeout <= 0 || cfg.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("server: timeouts must be positive"))
+	}
+	if cfg.Server.MaxHeaderBytes < 4096 {
+		errs = append(errs, errors.New("server: maxHeaderBytes must be at least 4096"))
+	}
+
+	if cfg.DB.DSN != "" {
+		if err := validateDSN(cfg.DB.DSN); err != nil {
+			errs = append(errs, fmt.Errorf("db.dsn: %w", err))
+		}
+	} else if cfg.DB.Host == "" || cfg.DB.Database == "" || cfg.DB.Username == "" {
+		errs = append(errs, errors.New("db: either dsn or host, database and username must be set"))
+	}
+	if cfg.DB.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("db: maxOpenConns must be positive"))
+	}
+	if cfg.DB.MaxIdleConns < 0 || cfg.DB.MaxIdleConns > cfg.DB.MaxOpenConns {
+		errs = append(errs, errors.New("db: maxIdleConns must be between 0 and maxOpenConns"))
+	}
+	if cfg.DB.ConnectTimeout <= 0 {
+		errs = append(errs, errors.New("db: connectTimeout must be positive"))
+	}
+
+	if cfg.Approval.Quorum < 1 {
+		errs = append(errs, errors.New("approval: quorum must be positive"))
+	}
+
+	if cfg.Pagination.DefaultLimit < 1 || cfg.Pagination.MaxLimit < cfg.Pagination.DefaultLimit {
+		errs = append(errs, errors.New("pagination: defaultLimit must be positive and not exceed maxLimit"))
+	}
+
+	if cfg.Idempotency.TTL <= 0 || cfg.Idempotency.CleanupEvery <= 0 {
+		errs = append(errs, errors.New("idempotency: ttl and cleanupEvery must be positive"))
+	}
+
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.IdleTimeout <= 0 || cfg.RateLimit.CleanupEvery <= 0 {
+			errs = append(errs, errors.New("rateLimit: idleTimeout and cleanupEvery must be positive"))
+		}
+		if cfg.RateLimit.MaxBuckets < 1 {
+			errs = append(errs, errors.New("rateLimit: maxBuckets must be positive"))
+		}
+		if cfg.RateLimit.PerIP.RPS < 0 || cfg.RateLimit.PerIP.Burst < 1 {
+			errs = append(errs, errors.New("rateLimit perIp: rps must not be negative and burst must be positive"))
+		}
+		limits := map[string]RouteLimit{"default": cfg.RateLimit.Default}
+		for route, l := range cfg.RateLimit.Routes {
+			limits[route] = l
+		}
+		for route, l := range limits {
+			if l.RPS < 0 || l.Burst < 1 {
+				errs = append(errs, fmt.Errorf("rateLimit %s: rps must not be negative and burst must be positive", route))
+			}
+			switch l.KeyBy {
+			case "", "user", "organization", "ip":
+			default:
+				errs = append(errs, fmt.Errorf("rateLimit %s: keyBy must be user, organization or ip", route))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateDSN(dsn string) error {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return errors.New("malformed URL")
+		}
+		if u.Host == "" {
+			return errors.New("host is missing")
+		}
+		return nil
+	}
+	if strings.Contains(dsn, "=") {
+		return nil
+	}
+	return errors.New("must be a postgres:// URL or a key=value connection string")
+}
+
+func (c DBConfig) ConnectionString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+
+	host := c.Host
+	if c.Port != "" {
+		host = net.JoinHostPort(c.Host, c.Port)
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.Username, c.Password),
+		Host:   host,
+		Path:   "/" + c.Database,
+	}
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+	return u.String()
+}
+
+// Redacted returns a copy of the config that is safe to print.
+func (cfg Config) Redacted() Config {
+	const mask = "*****"
+
+	if cfg.DB.Password != "" {
+		cfg.DB.Password = mask
+	}
+	if cfg.DB.DSN != "" {
+		if u, err := url.Parse(cfg.DB.DSN); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), mask)
+				cfg.DB.DSN = u.String()
+			}
+		} else if strings.Contains(cfg.DB.DSN, "password=") {
+			fields := strings.Fields(cfg.DB.DSN)
+			for i, f := range fields {
+				if strings.HasPrefix(f, "password=") {
+					fields[i] = "password=" + mask
+				}
+			}
+			cfg.DB.DSN = strings.Join(fields, " ")
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML understands the subset of YAML used by config files: nested
+// mappings, lists of scalars, comments and quoted or plain scalars.
+func parseYAML(content []byte) (map[string]any, error) {
+	type frame struct {
+		indent int
+		node   map[string]any
+	}
+
+	root := map[string]any{}
+	stack := []frame{{indent: -1, node: root}}
+
+	var listKey string
+	var listParent map[string]any
+	listIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := stripComment(scanner.Text())
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		if strings.Contains(raw, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		line := strings.TrimSpace(raw)
+
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			if listParent == nil || indent < listIndent {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNo)
+			}
+			listIndent = indent
+			item := strings.TrimSpace(strings.TrimPrefix(line, "-"))
+			list, _ := listParent[listKey].([]any)
+			listParent[listKey] = append(list, parseScalar(item))
+			continue
+		}
+		listParent = nil
+		listIndent = -1
+
+		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", lineNo)
+		}
+		if _, exists := parent[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+
+		if value == "" {
+			child := map[string]any{}
+			parent[key] = child
+			stack = append(stack, frame{indent: indent, node: child})
+
+			listKey = key
+			listParent = parent
+			continue
+		}
+
+		if value == "[]" {
+			parent[key] = []any{}
+			continue
+		}
+		parent[key] = parseScalar(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func stripComment(line string) string {
+	inQuote := rune(0)
+	for i, c := range line {
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func parseScalar(value string) any {
+	if len(value) >= 2 {
+		if value[0] == '"' && value[len(value)-1] == '"' {
+			if s, err := strconv.Unquote(value); err == nil {
+				return s
+			}
+		}
+		if value[0] == '\'' && value[len(value)-1] == '\'' {
+			return strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+	}
+
+	switch value {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case "null", "~":
+		return nil
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
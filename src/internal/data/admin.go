@@ -0,0 +1,299 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenderStatuses and BidStatuses list every status a tender or a bid can be
+// in, including the ones only reachable through dedicated actions.
+var (
+	TenderStatuses = []string{"Created", "Published", "Closed"}
+	BidStatuses    = []string{"Created", "Published", "Canceled", "Withdrawn"}
+)
+
+// TenderFilter selects tenders for operators regardless of visibility.
+// Empty fields do not filter; a zero limit returns every tender.
+type TenderFilter struct {
+	OrganizationId string
+	Status         string
+	Limit          int32
+	Offset         int32
+}
+
+func (m TenderModel) ListTenders(f TenderFilter) ([]*Tender, error) {
+	query := `
+		SELECT ` + tenderColumns + `
+		FROM tenders
+		WHERE ($1 = '' OR organization_id::text = $1)
+		AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id
+		LIMIT NULLIF($3, 0) OFFSET $4
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, f.OrganizationId, f.Status, f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanTenders(rows)
+}
+
+// BidFilter selects bids for operators regardless of status. Empty fields
+// do not filter; a zero limit returns every bid.
+type BidFilter struct {
+	TenderId string
+	AuthorId string
+	Status   string
+	Limit    int32
+	Offset   int32
+}
+
+func (m BidModel) ListBids(f BidFilter) ([]*Bid, error) {
+	query := `
+		SELECT ` + bidColumns + `
+		FROM bids
+		WHERE ($1 = '' OR tender_id::text = $1)
+		AND ($2 = '' OR author_id::text = $2)
+		AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC, id
+		LIMIT NULLIF($4, 0) OFFSET $5
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, f.TenderId, f.AuthorId, f.Status, f.Limit, f.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanBids(rows)
+}
+
+// AuditEntry records an operator action taken outside the API.
+type AuditEntry struct {
+	Id          string `json:"id"`
+	Actor       string `json:"actor"`
+	Action      string `json:"action"`
+	SubjectType string `json:"subjectType"`
+	SubjectId   string `json:"subjectId"`
+	Note        string `json:"note"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func insertAudit(q querier, entry *AuditEntry) error {
+	query := `
+		INSERT INTO admin_audit (id, actor, action, subject_type, subject_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entry.Id = uuid.New().String()
+	var createdAt time.Time
+	err := q.QueryRowContext(ctx, query, entry.Id, entry.Actor, entry.Action, entry.SubjectType, entry.SubjectId, entry.Note).Scan(&createdAt)
+	if err != nil {
+		return err
+	}
+	entry.CreatedAt = createdAt.Format(time.RFC3339)
+	return nil
+}
+
+// GetEntries lists audit entries, newest first, optionally only those about
+// one subject.
+func (m AuditModel) GetEntries(subjectId string, limit int32) ([]*AuditEntry, error) {
+	query := `
+		SELECT id, actor, action, subject_type, subject_id, note, created_at
+		FROM admin_audit
+		WHERE ($1 = '' OR subject_id::text = $1)
+		ORDER BY created_at DESC
+		LIMIT NULLIF($2, 0)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, subjectId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var createdAt time.Time
+		err := rows.Scan(&entry.Id, &entry.Actor, &entry.Action, &entry.SubjectType, &entry.SubjectId, &entry.Note, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = createdAt.Format(time.RFC3339)
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// QuorumStatus is the approval state of the current version of a published
+// bid on a published tender. Responsible is the number of users of the
+// tender organization, which caps the quorum for small organizations.
+type QuorumStatus struct {
+	TenderId    string `json:"tenderId"`
+	BidId       string `json:"bidId"`
+	BidVersion  int    `json:"bidVersion"`
+	Approvals   int    `json:"approvals"`
+	Responsible int    `json:"responsible"`
+}
+
+// GetQuorumStatus counts approvals of the bids that can still close their
+// tender, either for one tender or for all of them.
+func (m BidModel) GetQuorumStatus(tenderId string) ([]*QuorumStatus, error) {
+	query := `
+		SELECT t.id, b.id, b.version,
+			(SELECT count(*) FROM bids_approvals a WHERE a.bid_id = b.id AND a.bid_version = b.version),
+			(SELECT count(*) FROM organization_responsible r WHERE r.organization_id = t.organization_id)
+		FROM bids b JOIN tenders t ON t.id = b.tender_id
+		WHERE t.status = 'Published' AND b.status = 'Published'
+		AND ($1 = '' OR t.id::text = $1)
+		ORDER BY t.id, b.id
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, tenderId)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	res := []*QuorumStatus{}
+	for rows.Next() {
+		var s QuorumStatus
+		if err := rows.Scan(&s.TenderId, &s.BidId, &s.BidVersion, &s.Approvals, &s.Responsible); err != nil {
+			return nil, err
+		}
+		res = append(res, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Membership is an organization a user is responsible for.
+type Membership struct {
+	OrganizationId   string `json:"organizationId"`
+	OrganizationName string `json:"organizationName"`
+}
+
+type UserMemberships struct {
+	UserId        string       `json:"userId"`
+	Username      string       `json:"username"`
+	Organizations []Membership `json:"organizations"`
+}
+
+func (m TenderModel) GetUserMemberships(username string) (*UserMemberships, error) {
+	userId, err := m.GetUserID(username)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT o.id, o.name
+		FROM organization_responsible r JOIN organization o ON o.id = r.organization_id
+		WHERE r.user_id = $1
+		ORDER BY o.name
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	res := &UserMemberships{UserId: userId, Username: username, Organizations: []Membership{}}
+	for rows.Next() {
+		var membership Membership
+		if err := rows.Scan(&membership.OrganizationId, &membership.OrganizationName); err != nil {
+			return nil, err
+		}
+		res.Organizations = append(res.Organizations, membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+var ErrInvalidKeep = errors.New("at least one previous version must be kept")
+
+// purgeHistory deletes stored versions of tenders and bids except the keep
+// most recent ones before the current version. Purged versions can no
+// longer be rolled back to.
+func purgeHistory(q querier, keep int) (tenders, bids int64, err error) {
+	if keep < 1 {
+		return 0, 0, ErrInvalidKeep
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	res, err := q.ExecContext(ctx, `
+		DELETE FROM tenders_history h USING tenders t
+		WHERE h.tender_id = t.id AND h.version < t.version - $1
+	`, keep)
+	if err != nil {
+		return 0, 0, err
+	}
+	if tenders, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	res, err = q.ExecContext(ctx, `
+		DELETE FROM bids_history h USING bids b
+		WHERE h.bid_id = b.id AND h.version < b.version - $1
+	`, keep)
+	if err != nil {
+		return 0, 0, err
+	}
+	if bids, err = res.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+	return tenders, bids, nil
+}
+
+func (t *Tx) InsertAudit(entry *AuditEntry) error {
+	return insertAudit(t.tx, entry)
+}
+
+func (t *Tx) ChangeBidStatus(bidId, status string) (*Bid, error) {
+	return changeBidStatus(t.tx, bidId, status)
+}
+
+func (t *Tx) PurgeHistory(keep int) (tenders, bids int64, err error) {
+	return purgeHistory(t.tx, keep)
+}
@@ -1,11 +1,14 @@
 package data
 
 import (
+	"avitotask/internal/validator"
 	"context"
 	"database/sql"
 	"errors"
 	"log"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 //
@@ -24,29 +27,75 @@ type Bid struct {
 	CreatedAt   string `json:"createdAt"`
 }
 
+func BidRules() validator.FieldRules {
+	return validator.FieldRules{
+		"name":        {validator.Required(), validator.MaxRunes(100)},
+		"description": {validator.Required(), validator.MaxRunes(500)},
+	}
+}
+
 type BidModel struct {
 	DB *sql.DB
 }
 
+// bidColumns lists the columns scanBid expects, in order. Queries name them
+// explicitly so that new columns do not shift the mapping.
+const bidColumns = `id, name, description, status, tender_id, author_type, author_id, version, created_at`
+
+func scanBid(scan func(dest ...any) error) (*Bid, error) {
+	var bid Bid
+
+	err := scan(&bid.Id, &bid.Name, &bid.Description, &bid.Status, &bid.TenderId, &bid.AuthorType, &bid.AuthorId, &bid.Version, &bid.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &bid, nil
+}
+
+func scanBids(rows *sql.Rows) ([]*Bid, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	bids := []*Bid{}
+	for rows.Next() {
+		bid, err := scanBid(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return bids, nil
+}
+
 var (
 	ErrBidNotFound         = errors.New("bid does not exist")
 	ErrBidOrTenderNotFound = errors.New("bid or tender does not exist")
 	ErrBidVersionNotFound  = errors.New("bid version does not exist")
+	ErrApprovalNotFound    = errors.New("approval does not exist")
+	ErrApprovalExists      = errors.New("approval already exists")
 )
 
 func (m BidModel) GetBidById(bidId string) (*Bid, error) {
+	return getBidById(m.DB, bidId)
+}
+
+func getBidById(q querier, bidId string) (*Bid, error) {
 	query :=
 		`
-		SELECT * FROM bids WHERE id=$1
+		SELECT ` + bidColumns + ` FROM bids WHERE id=$1
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 
 	defer cancel()
 
-	var bid Bid
-	row := m.DB.QueryRowContext(ctx, query, bidId)
-
-	err := row.Scan(&bid.Id, &bid.Name, &bid.Description, &bid.Status, &bid.TenderId, &bid.AuthorType, &bid.AuthorId, &bid.Version, &bid.CreatedAt)
+	bid, err := scanBid(q.QueryRowContext(ctx, query, bidId).Scan)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -54,11 +103,15 @@ func (m BidModel) GetBidById(bidId string) (*Bid, error) {
 		}
 		return nil, err
 	}
-	return &bid, nil
+	return bid, nil
 
 }
 
 func (m BidModel) InsertBid(bid *Bid) error {
+	return insertBid(m.DB, bid)
+}
+
+func insertBid(q querier, bid *Bid) error {
 	query := `
 		INSERT INTO bids (id, name, description, status, tender_id, author_type, author_id, version, created_at) 
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
@@ -68,7 +121,7 @@ func (m BidModel) InsertBid(bid *Bid) error {
 
 	args := []interface{}{bid.Id, bid.Name, bid.Description, bid.Status, bid.TenderId, bid.AuthorType, bid.AuthorId, bid.Version, bid.CreatedAt}
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	_, err := q.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -78,8 +131,8 @@ func (m BidModel) InsertBid(bid *Bid) error {
 func (m BidModel) GetMyBids(limit, offset int32, groupId, userId string) ([]*Bid, error) {
 	query :=
 		`
-		SELECT * FROM bids 
-		WHERE author_id=$1 OR author_id=$2 
+		SELECT ` + bidColumns + ` FROM bids
+		WHERE author_id=$1 OR author_id=$2
 		ORDER BY name
 		LIMIT $3 OFFSET $4
 	`
@@ -93,65 +146,33 @@ func (m BidModel) GetMyBids(limit, offset int32, groupId, userId string) ([]*Bid
 		return nil, err
 	}
 
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	bids := []*Bid{}
-	for rows.Next() {
-		var bid Bid
-
-		err := rows.Scan(
-			&bid.Id,
-			&bid.Name,
-			&bid.Description,
-			&bid.Status,
-			&bid.TenderId,
-			&bid.AuthorType,
-			&bid.AuthorId,
-			&bid.Version,
-			&bid.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		bids = append(bids, &bid)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return bids, nil
+	return scanBids(rows)
 
 }
 
 func (m BidModel) ChangeBidStatus(bidId, status string) (*Bid, error) {
+	return changeBidStatus(m.DB, bidId, status)
+}
+
+func changeBidStatus(q querier, bidId, status string) (*Bid, error) {
 
 	query :=
 		`
 		UPDATE bids SET status=$1
 		WHERE id=$2
-		RETURNING *
-	`
+		RETURNING ` + bidColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var bid Bid
-
-	err := m.DB.QueryRowContext(ctx, query, status, bidId).Scan(
-		&bid.Id, &bid.Name, &bid.Description, &bid.Status, &bid.TenderId, &bid.AuthorType, &bid.AuthorId, &bid.Version, &bid.CreatedAt)
+	bid, err := scanBid(q.QueryRowContext(ctx, query, status, bidId).Scan)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrBidNotFound
 		}
 		return nil, err
 	}
-	return &bid, nil
+	return bid, nil
 
 }
 
@@ -183,7 +204,7 @@ func (m BidModel) GetBidStatus(bidId string) (string, error) {
 
 func (m BidModel) GetBidsByTenderId(limit, offset int32, tenderId string) ([]*Bid, error) {
 	query := `
-		SELECT * FROM bids 
+		SELECT ` + bidColumns + ` FROM bids
 		WHERE tender_id=$1 AND status='Published'
 		ORDER BY name
 		LIMIT $2 OFFSET $3
@@ -197,35 +218,8 @@ func (m BidModel) GetBidsByTenderId(limit, offset int32, tenderId string) ([]*Bi
 		return nil, err
 	}
 
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	bids := []*Bid{}
-	for rows.Next() {
-		var bid Bid
-
-		err := rows.Scan(
-			&bid.Id,
-			&bid.Name,
-			&bid.Description,
-			&bid.Status,
-			&bid.TenderId,
-			&bid.AuthorType,
-			&bid.AuthorId,
-			&bid.Version,
-			&bid.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		bids = append(bids, &bid)
-	}
-
-	if err = rows.Err(); err != nil {
+	bids, err := scanBids(rows)
+	if err != nil {
 		return nil, err
 	}
 	if len(bids) == 0 {
@@ -237,48 +231,49 @@ func (m BidModel) GetBidsByTenderId(limit, offset int32, tenderId string) ([]*Bi
 }
 
 func (m BidModel) EditBid(bidId string, newBid Bid) (*Bid, error) {
-	updateQuery := `
-		UPDATE bids SET name=coalesce(NULLIF($1,''), name), description=coalesce(NULLIF($2,''), description), version=$3 
-		WHERE id=$4
-		RETURNING id, name, description, status, tender_id, author_type,author_id, version, created_at
-	`
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	currentBid := Bid{}
 
-	err = tx.QueryRow("SELECT * FROM bids WHERE id = $1", bidId).Scan(
-		&currentBid.Id, &currentBid.Name, &currentBid.Description, &currentBid.Status, &currentBid.TenderId, &currentBid.AuthorType, &currentBid.AuthorId, &currentBid.Version, &currentBid.CreatedAt)
+	bid, err := editBid(tx, bidId, newBid)
 	if err != nil {
 		tx.Rollback()
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrBidNotFound
-		}
 		return nil, err
 	}
 
-	_, err = tx.Exec(`INSERT INTO bids_history (bid_id, name, description, version) 
-	VALUES ($1, $2, $3, $4)`, currentBid.Id, currentBid.Name, currentBid.Description, currentBid.Version)
-
-	if err != nil {
-		tx.Rollback()
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	row := tx.QueryRow(updateQuery, newBid.Name, newBid.Description, currentBid.Version+1, bidId)
+	return bid, nil
+}
+
+// editBid keeps the current version in the history and applies the
+// non-empty fields of newBid as the next one. q must be a transaction.
+func editBid(q querier, bidId string, newBid Bid) (*Bid, error) {
+	updateQuery := `
+		UPDATE bids SET name=coalesce(NULLIF($1,''), name), description=coalesce(NULLIF($2,''), description), version=$3 
+		WHERE id=$4
+		RETURNING ` + bidColumns
 
-	err = row.Scan(&newBid.Id, &newBid.Name, &newBid.Description, &newBid.Status, &newBid.TenderId, &newBid.AuthorType, &newBid.AuthorId, &newBid.Version, &newBid.CreatedAt)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
+	currentBid, err := scanBid(q.QueryRowContext(ctx, "SELECT "+bidColumns+" FROM bids WHERE id = $1", bidId).Scan)
 	if err != nil {
-		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBidNotFound
+		}
 		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
+	_, err = q.ExecContext(ctx, `INSERT INTO bids_history (bid_id, name, description, version) 
+	VALUES ($1, $2, $3, $4)`, currentBid.Id, currentBid.Name, currentBid.Description, currentBid.Version)
+	if err != nil {
 		return nil, err
 	}
 
-	return &newBid, nil
+	return scanBid(q.QueryRowContext(ctx, updateQuery, newBid.Name, newBid.Description, currentBid.Version+1, bidId).Scan)
 }
 
 func (m BidModel) RollbackBid(targetVersion int, bidId string) (*Bid, error) {
@@ -292,8 +287,8 @@ func (m BidModel) RollbackBid(targetVersion int, bidId string) (*Bid, error) {
 		`
 		UPDATE bids SET name=$1, description=$2, version=version+1
 		WHERE id=$3
-		RETURNING *
-	`
+		RETURNING ` + bidColumns
+
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return nil, err
@@ -331,10 +326,8 @@ func (m BidModel) RollbackBid(targetVersion int, bidId string) (*Bid, error) {
 		return nil, err
 	}
 
-	bid := Bid{}
-
 	row = tx.QueryRow(rollbackTenderQuery, historyParams.name, historyParams.description, bidId)
-	err = row.Scan(&bid.Id, &bid.Name, &bid.Description, &bid.Status, &bid.TenderId, &bid.AuthorType, &bid.AuthorId, &bid.Version, &bid.CreatedAt)
+	bid, err := scanBid(row.Scan)
 
 	if err != nil {
 		tx.Rollback()
@@ -347,8 +340,58 @@ func (m BidModel) RollbackBid(targetVersion int, bidId string) (*Bid, error) {
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
-	return &bid, nil
+	return bid, nil
+
+}
 
+// WithdrawBid takes the bid out of review. Its approvals are kept, they
+// stop counting once the bid is resubmitted under a new version.
+func (m BidModel) WithdrawBid(bidId string) (*Bid, error) {
+	return changeBidStatus(m.DB, bidId, "Withdrawn")
+}
+
+// ResubmitBid publishes a withdrawn bid again as a new version, so the
+// decision on it starts over. The previous version is kept in the history.
+func (m BidModel) ResubmitBid(bidId string) (*Bid, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	currentBid, err := scanBid(tx.QueryRowContext(ctx, "SELECT "+bidColumns+" FROM bids WHERE id = $1 FOR UPDATE", bidId).Scan)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBidNotFound
+		}
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO bids_history (bid_id, name, description, version)
+	VALUES ($1, $2, $3, $4)`, currentBid.Id, currentBid.Name, currentBid.Description, currentBid.Version)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	resubmitQuery := `
+		UPDATE bids SET status='Published', version=version+1
+		WHERE id=$1
+		RETURNING ` + bidColumns
+
+	bid, err := scanBid(tx.QueryRowContext(ctx, resubmitQuery, bidId).Scan)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return bid, nil
 }
 
 /*func (m *BidModel) ApproveDecision(bidId string) error {
@@ -362,48 +405,76 @@ func (m BidModel) RollbackBid(targetVersion int, bidId string) (*Bid, error) {
 }*/
 
 func (m BidModel) RejectDecision(bidId string) (*Bid, error) {
-	cancelBidQuery := `UPDATE bids SET status='Canceled' 
+	return rejectDecision(m.DB, bidId)
+}
+
+func rejectDecision(q querier, bidId string) (*Bid, error) {
+	cancelBidQuery := `UPDATE bids SET status='Canceled'
 	WHERE id=$1
-	RETURNING *`
+	RETURNING ` + bidColumns
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	var bid Bid
-	row := m.DB.QueryRowContext(ctx, cancelBidQuery, bidId)
-	err := row.Scan(&bid.Id, &bid.Name, &bid.Description, &bid.Status, &bid.TenderId, &bid.AuthorType, &bid.AuthorId, &bid.Version, &bid.CreatedAt)
+	bid, err := scanBid(q.QueryRowContext(ctx, cancelBidQuery, bidId).Scan)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrBidNotFound
 		}
 		return nil, err
 	}
-	return &bid, nil
+	return bid, nil
 }
 
+// ApproveDecision records the user's approval of the bid's current version.
 func (m BidModel) ApproveDecision(bidId, userId string) error {
+	return approveDecision(m.DB, bidId, userId)
+}
+
+func approveDecision(q querier, bidId, userId string) error {
 	query := `
-		INSERT INTO bids_approvals (bid_id, user_id)
-		VALUES ($1, $2)
+		INSERT INTO bids_approvals (bid_id, user_id, bid_version)
+		SELECT id, $2, version FROM bids WHERE id=$1
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, bidId, userId)
+	result, err := q.ExecContext(ctx, query, bidId, userId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrApprovalExists
+		}
+		return err
+	}
 
-	return err
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrBidNotFound
+	}
+	return nil
 }
 
+// ApprovalCount returns the users who approved the current version of the
+// bid. Approvals of earlier versions do not count towards the quorum.
 func (m BidModel) ApprovalCount(bidId string) ([]string, error) {
+	return approvalCount(m.DB, bidId)
+}
+
+func approvalCount(q querier, bidId string) ([]string, error) {
 	query :=
 		`
-		SELECT user_id FROM bids_approvals
-		WHERE bid_id=$1
+		SELECT a.user_id FROM bids_approvals a
+		JOIN bids b ON b.id = a.bid_id AND b.version = a.bid_version
+		WHERE a.bid_id=$1
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, bidId)
+	rows, err := q.QueryContext(ctx, query, bidId)
 	if err != nil {
 		return nil, err
 	}
@@ -433,3 +504,228 @@ func (m BidModel) ApprovalCount(bidId string) ([]string, error) {
 	}
 	return ids, nil
 }
+
+type VersionApprovals struct {
+	Version   int  `json:"version"`
+	Approvals int  `json:"approvals"`
+	Current   bool `json:"current"`
+}
+
+// ApprovalsByVersion returns the number of approvals each version of the bid
+// has received, newest version first. The current version is always listed.
+func (m BidModel) ApprovalsByVersion(bidId string) ([]*VersionApprovals, error) {
+	query :=
+		`
+		SELECT v.version, count(a.id), v.version = b.version
+		FROM bids b
+		JOIN (
+			SELECT version FROM bids WHERE id=$1
+			UNION
+			SELECT bid_version FROM bids_approvals WHERE bid_id=$1
+		) v ON true
+		LEFT JOIN bids_approvals a ON a.bid_id = b.id AND a.bid_version = v.version
+		WHERE b.id=$1
+		GROUP BY v.version, b.version
+		ORDER BY v.version DESC
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bidId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	versions := []*VersionApprovals{}
+	for rows.Next() {
+		var version VersionApprovals
+
+		err := rows.Scan(&version.Version, &version.Approvals, &version.Current)
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, &version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrBidNotFound
+	}
+	return versions, nil
+}
+
+type Approval struct {
+	UserId     string `json:"userId"`
+	Username   string `json:"username"`
+	BidVersion int    `json:"bidVersion"`
+	Current    bool   `json:"current"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// GetApprovals lists every approval of the bid, newest version first.
+func (m BidModel) GetApprovals(bidId string) ([]*Approval, error) {
+	query :=
+		`
+		SELECT a.user_id, coalesce(e.username, ''), a.bid_version, a.bid_version = b.version, a.created_at
+		FROM bids_approvals a
+		JOIN bids b ON b.id = a.bid_id
+		LEFT JOIN employee e ON e.id = a.user_id
+		WHERE a.bid_id=$1
+		ORDER BY a.bid_version DESC, a.created_at
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bidId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	approvals := []*Approval{}
+	for rows.Next() {
+		var approval Approval
+
+		err := rows.Scan(&approval.UserId, &approval.Username, &approval.BidVersion, &approval.Current, &approval.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		approvals = append(approvals, &approval)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// RevokeApproval removes the user's approval of the bid's current version.
+func (m BidModel) RevokeApproval(bidId, userId string) error {
+	query := `
+		DELETE FROM bids_approvals a
+		USING bids b
+		WHERE a.bid_id = b.id AND a.bid_version = b.version AND a.bid_id=$1 AND a.user_id=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, bidId, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrApprovalNotFound
+	}
+	return nil
+}
+
+type PendingDecision struct {
+	TenderId   string `json:"tenderId"`
+	TenderName string `json:"tenderName"`
+	Bid        *Bid   `json:"bid"`
+}
+
+// GetPendingDecisions returns published bids on published tenders of the
+// user's organizations whose current version the user has not approved yet.
+func (m BidModel) GetPendingDecisions(limit, offset int32, userId string) ([]*PendingDecision, error) {
+	query :=
+		`
+		SELECT t.id, t.name, ` + prefixColumns("b", bidColumns) + `
+		FROM bids b
+		JOIN tenders t ON t.id = b.tender_id
+		WHERE t.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id=$1)
+			AND t.status = 'Published' AND b.status = 'Published'
+			AND NOT EXISTS (
+				SELECT 1 FROM bids_approvals a
+				WHERE a.bid_id = b.id AND a.bid_version = b.version AND a.user_id=$1
+			)
+		ORDER BY b.created_at, b.name
+		LIMIT $2 OFFSET $3
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	decisions := []*PendingDecision{}
+	for rows.Next() {
+		var decision PendingDecision
+
+		// The tender columns come first, the rest is left to scanBid.
+		bid, err := scanBid(func(dest ...any) error {
+			return rows.Scan(append([]any{&decision.TenderId, &decision.TenderName}, dest...)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		decision.Bid = bid
+		decisions = append(decisions, &decision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return decisions, nil
+}
+
+// TenderBidStats summarizes the bids on a tender for its owners. Approvals
+// only count those given to the current version of each bid.
+type TenderBidStats struct {
+	Bids          int `json:"bids"`
+	PublishedBids int `json:"publishedBids"`
+	Approvals     int `json:"approvals"`
+}
+
+func (m BidModel) GetTenderBidStats(tenderId string) (*TenderBidStats, error) {
+	query := `
+		SELECT
+			(SELECT count(*) FROM bids WHERE tender_id=$1),
+			(SELECT count(*) FROM bids WHERE tender_id=$1 AND status='Published'),
+			(SELECT count(*) FROM bids_approvals a
+				JOIN bids b ON b.id = a.bid_id AND b.version = a.bid_version
+				WHERE b.tender_id=$1)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var stats TenderBidStats
+	err := m.DB.QueryRowContext(ctx, query, tenderId).Scan(&stats.Bids, &stats.PublishedBids, &stats.Approvals)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
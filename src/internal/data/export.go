@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// ExportOptions select the optional columns of a report. History adds a row
+// for every previous version after the current one; Approvals adds approval
+// counts.
+type ExportOptions struct {
+	History   bool
+	Approvals bool
+}
+
+type TenderExportRow struct {
+	Tender
+	Current   bool
+	Bids      int
+	Approvals int
+}
+
+type BidExportRow struct {
+	Bid
+	Current   bool
+	Approvals int
+	Approvers []string
+}
+
+// ExportMyTenders passes the tenders of an organization to fn one row at a
+// time, in the order and with the paging of GetMyTenders. A zero limit
+// exports every tender. The version columns of history rows come from
+// tenders_history; status and visibility are those of the current version.
+func (m TenderModel) ExportMyTenders(ctx context.Context, limit, offset int32, organizationId string, opts ExportOptions, fn func(*TenderExportRow) error) error {
+	query := `
+		WITH page AS (
+			SELECT id, row_number() OVER (ORDER BY name) AS ord
+			FROM tenders
+			WHERE organization_id=$1
+			ORDER BY name
+			LIMIT NULLIF($2, 0) OFFSET $3
+		), versions AS (
+			SELECT t.id, t.name, t.description, t.service_type, t.version, true AS current, page.ord
+			FROM tenders t JOIN page ON page.id = t.id
+			UNION ALL
+			SELECT h.tender_id, h.name, h.description, h.service_type, h.version, false, page.ord
+			FROM tenders_history h
+			JOIN tenders t ON t.id = h.tender_id
+			JOIN page ON page.id = h.tender_id
+			WHERE $4 AND h.version <> t.version
+		)
+		SELECT t.id, v.name, v.description, v.service_type, t.status, t.organization_id,
+			t.visibility, v.version, t.created_at, t.deadline, v.current,
+			CASE WHEN $5 AND v.current THEN (SELECT count(*) FROM bids b WHERE b.tender_id = t.id) ELSE 0 END,
+			CASE WHEN $5 AND v.current THEN (SELECT count(*) FROM bids_approvals a
+				JOIN bids b ON b.id = a.bid_id AND b.version = a.bid_version
+				WHERE b.tender_id = t.id) ELSE 0 END
+		FROM versions v JOIN tenders t ON t.id = v.id
+		ORDER BY v.ord, v.version DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, organizationId, limit, offset, opts.History, opts.Approvals)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	for rows.Next() {
+		var row TenderExportRow
+		tender, err := scanTender(func(dest ...any) error {
+			return rows.Scan(append(dest, &row.Current, &row.Bids, &row.Approvals)...)
+		})
+		if err != nil {
+			return err
+		}
+		row.Tender = *tender
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExportTenderBids passes the published bids of a tender to fn one row at a
+// time, in the order and with the paging of GetBidsByTenderId. A zero limit
+// exports every bid. Approvals are counted for the version of each row.
+func (m BidModel) ExportTenderBids(ctx context.Context, limit, offset int32, tenderId string, opts ExportOptions, fn func(*BidExportRow) error) error {
+	query := `
+		WITH page AS (
+			SELECT id, row_number() OVER (ORDER BY name) AS ord
+			FROM bids
+			WHERE tender_id=$1 AND status='Published'
+			ORDER BY name
+			LIMIT NULLIF($2, 0) OFFSET $3
+		), versions AS (
+			SELECT b.id, b.name, b.description, b.version, true AS current, page.ord
+			FROM bids b JOIN page ON page.id = b.id
+			UNION ALL
+			SELECT h.bid_id, h.name, h.description, h.version, false, page.ord
+			FROM bids_history h
+			JOIN bids b ON b.id = h.bid_id
+			JOIN page ON page.id = h.bid_id
+			WHERE $4 AND h.version <> b.version
+		)
+		SELECT b.id, v.name, v.description, b.status, b.tender_id, b.author_type, b.author_id,
+			v.version, b.created_at, v.current,
+			CASE WHEN $5 THEN (SELECT count(*) FROM bids_approvals a
+				WHERE a.bid_id = b.id AND a.bid_version = v.version) ELSE 0 END,
+			CASE WHEN $5 THEN (SELECT array_agg(e.username ORDER BY e.username)
+				FROM bids_approvals a JOIN employee e ON e.id = a.user_id
+				WHERE a.bid_id = b.id AND a.bid_version = v.version) ELSE NULL END
+		FROM versions v JOIN bids b ON b.id = v.id
+		ORDER BY v.ord, v.version DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, tenderId, limit, offset, opts.History, opts.Approvals)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	for rows.Next() {
+		var row BidExportRow
+		bid, err := scanBid(func(dest ...any) error {
+			return rows.Scan(append(dest, &row.Current, &row.Approvals, pq.Array(&row.Approvers))...)
+		})
+		if err != nil {
+			return err
+		}
+		row.Bid = *bid
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key does not exist")
+
+// IdempotentRequest is a request sent with an Idempotency-Key header. Keys
+// are unique within a scope, which identifies the caller, so that clients
+// cannot see each other's responses. Status is zero while the first request
+// with the key is still running.
+type IdempotentRequest struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+type IdempotencyModel struct {
+	DB *sql.DB
+}
+
+// Claim records key for a request that is about to run. It reports false
+// if a live record for the key already exists in scope; an expired one is
+// replaced.
+func (m IdempotencyModel) Claim(scope, key, fingerprint string, ttl time.Duration) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, now() + $4 * interval '1 millisecond')
+		ON CONFLICT (scope, key) DO UPDATE
+			SET fingerprint = EXCLUDED.fingerprint,
+				status = NULL,
+				content_type = NULL,
+				body = NULL,
+				created_at = now(),
+				expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= now()
+		RETURNING key
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var claimed string
+	err := m.DB.QueryRowContext(ctx, query, scope, key, fingerprint, ttl.Milliseconds()).Scan(&claimed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (m IdempotencyModel) Get(scope, key string) (*IdempotentRequest, error) {
+	query := `
+		SELECT scope, key, fingerprint, coalesce(status, 0), coalesce(content_type, ''), coalesce(body, ''), expires_at
+		FROM idempotency_keys
+		WHERE scope=$1 AND key=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var req IdempotentRequest
+	err := m.DB.QueryRowContext(ctx, query, scope, key).Scan(&req.Scope, &req.Key, &req.Fingerprint, &req.Status, &req.ContentType, &req.Body, &req.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Complete stores the response to replay for key.
+func (m IdempotencyModel) Complete(scope, key string, status int, contentType string, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status=$3, content_type=$4, body=$5
+		WHERE scope=$1 AND key=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, scope, key, status, contentType, body)
+	return err
+}
+
+// Release forgets key, so that a request that did not finish can be sent
+// again with it.
+func (m IdempotencyModel) Release(scope, key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE scope=$1 AND key=$2`, scope, key)
+	return err
+}
+
+func (m IdempotencyModel) DeleteExpired() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	res, err := m.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,63 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrImportNotFound = errors.New("external id has not been imported")
+	ErrImportExists   = errors.New("external id is being imported by another request")
+)
+
+// getImportedTender returns the tender an organization created for an
+// external id of its own system.
+func getImportedTender(q querier, organizationId, externalId string) (string, error) {
+	query := `
+		SELECT tender_id FROM tender_imports
+		WHERE organization_id=$1 AND external_id=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var tenderId string
+	err := q.QueryRowContext(ctx, query, organizationId, externalId).Scan(&tenderId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrImportNotFound
+		}
+		return "", err
+	}
+	return tenderId, nil
+}
+
+func insertTenderImport(q querier, organizationId, externalId, tenderId string) error {
+	query := `
+		INSERT INTO tender_imports (organization_id, external_id, tender_id)
+		VALUES ($1, $2, $3)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := q.ExecContext(ctx, query, organizationId, externalId, tenderId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrImportExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (t *Tx) GetImportedTender(organizationId, externalId string) (string, error) {
+	return getImportedTender(t.tx, organizationId, externalId)
+}
+
+func (t *Tx) InsertTenderImport(organizationId, externalId, tenderId string) error {
+	return insertTenderImport(t.tx, organizationId, externalId, tenderId)
+}
@@ -0,0 +1,493 @@
+package data_test
+
+import (
+	"avitotask/internal/data"
+	"avitotask/internal/pgtest"
+	"avitotask/internal/seed"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	pgtest.Main(m)
+}
+
+// fixture is an empty schema with the organizations and employees of a
+// small seed, but no tenders.
+type fixture struct {
+	models data.Models
+	seeded *seed.Result
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	models := data.NewModels(pgtest.Open(t))
+	if err := seed.Prepare(models); err != nil {
+		t.Fatal(err)
+	}
+	opts := seed.DefaultOptions()
+	opts.Tenders = 0
+	seeded, err := seed.Run(models, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{models: models, seeded: seeded}
+}
+
+func (f *fixture) organization(i int) string {
+	return f.seeded.Organizations[i].Id
+}
+
+func (f *fixture) member(organization, i int) string {
+	return f.seeded.Members[f.organization(organization)][i]
+}
+
+func (f *fixture) tender(t *testing.T, organizationId, status string) *data.Tender {
+	t.Helper()
+
+	tender := &data.Tender{
+		Id:             uuid.New().String(),
+		Name:           "Tender",
+		Description:    "Original description",
+		ServiceType:    "Construction",
+		Status:         status,
+		OrganizationId: organizationId,
+		Visibility:     data.VisibilityPublic,
+		Version:        1,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+	}
+	if err := f.models.Tenders.InsertTender(tender); err != nil {
+		t.Fatal(err)
+	}
+	return tender
+}
+
+func (f *fixture) bid(t *testing.T, tenderId, authorId, status string) *data.Bid {
+	t.Helper()
+
+	bid := &data.Bid{
+		Id:          uuid.New().String(),
+		Name:        "Bid",
+		Description: "Original description",
+		Status:      status,
+		TenderId:    tenderId,
+		AuthorType:  "Organization",
+		AuthorId:    authorId,
+		Version:     1,
+		CreatedAt:   time.Now().Format(time.RFC3339),
+	}
+	if err := f.models.Bids.InsertBid(bid); err != nil {
+		t.Fatal(err)
+	}
+	return bid
+}
+
+func TestMigrations(t *testing.T) {
+	models := data.NewModels(pgtest.Open(t))
+
+	for i := 0; i < 2; i++ {
+		if err := seed.Prepare(models); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	version, err := models.Tables.CurrentSchemaVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != data.SchemaVersion() {
+		t.Errorf("got schema version %d, want %d", version, data.SchemaVersion())
+	}
+}
+
+func TestSeedIsDeterministic(t *testing.T) {
+	run := func(seedValue int64) *seed.Result {
+		models := data.NewModels(pgtest.Open(t))
+		if err := seed.Prepare(models); err != nil {
+			t.Fatal(err)
+		}
+		opts := seed.DefaultOptions()
+		opts.Seed = seedValue
+		res, err := seed.Run(models, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := seed.Run(models, opts); !errors.Is(err, seed.ErrAlreadySeeded) {
+			t.Errorf("second run: got error %v, want %v", err, seed.ErrAlreadySeeded)
+		}
+		return res
+	}
+
+	type tenderKey struct {
+		id, name, status string
+		version          int
+	}
+	tenders := func(res *seed.Result) []tenderKey {
+		var keys []tenderKey
+		for _, tender := range res.Tenders {
+			keys = append(keys, tenderKey{tender.Id, tender.Name, tender.Status, tender.Version})
+		}
+		return keys
+	}
+	type bidKey struct {
+		id, author, status string
+		version            int
+	}
+	bids := func(res *seed.Result) []bidKey {
+		var keys []bidKey
+		for _, bid := range res.Bids {
+			keys = append(keys, bidKey{bid.Id, bid.AuthorId, bid.Status, bid.Version})
+		}
+		return keys
+	}
+
+	first, second, other := run(7), run(7), run(8)
+
+	if !slices.Equal(tenders(first), tenders(second)) {
+		t.Error("tenders differ between runs with the same seed")
+	}
+	if !slices.Equal(bids(first), bids(second)) {
+		t.Error("bids differ between runs with the same seed")
+	}
+	if first.Tenders[0].Id == other.Tenders[0].Id {
+		t.Error("runs with different seeds produced the same ids")
+	}
+
+	for _, status := range data.TenderStatuses {
+		if !slices.ContainsFunc(first.Tenders, func(tender *data.Tender) bool { return tender.Status == status }) {
+			t.Errorf("no tender in status %s", status)
+		}
+	}
+	for _, status := range data.BidStatuses {
+		if !slices.ContainsFunc(first.Bids, func(bid *data.Bid) bool { return bid.Status == status }) {
+			t.Errorf("no bid in status %s", status)
+		}
+	}
+}
+
+func TestSeedClosesTendersAtQuorum(t *testing.T) {
+	models := data.NewModels(pgtest.Open(t))
+	if err := seed.Prepare(models); err != nil {
+		t.Fatal(err)
+	}
+	opts := seed.DefaultOptions()
+	res, err := seed.Run(models, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := models.Bids.GetQuorumStatus("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Approvals >= min(opts.Quorum, s.Responsible) {
+			t.Errorf("bid %s on published tender %s has reached the quorum", s.BidId, s.TenderId)
+		}
+	}
+
+	for _, tender := range res.Tenders {
+		if tender.Status != "Closed" {
+			continue
+		}
+		bids, err := models.Bids.ListBids(data.BidFilter{TenderId: tender.Id, Status: "Published"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reached := slices.ContainsFunc(bids, func(bid *data.Bid) bool {
+			approvers, err := models.Bids.ApprovalCount(bid.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return len(approvers) >= min(opts.Quorum, opts.Employees)
+		})
+		if !reached {
+			t.Errorf("closed tender %s has no bid approved by the quorum", tender.Id)
+		}
+	}
+}
+
+func TestTenderVersioning(t *testing.T) {
+	f := newFixture(t)
+	tender := f.tender(t, f.organization(0), "Created")
+
+	updated, err := f.models.Tenders.UpdateTender(tender.Id, data.Tender{Name: "Renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 2 || updated.Name != "Renamed" || updated.Description != tender.Description {
+		t.Errorf("after update got version %d, name %q, description %q", updated.Version, updated.Name, updated.Description)
+	}
+
+	old, err := f.models.Tenders.GetTenderVersion(tender.Id, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Name != tender.Name {
+		t.Errorf("version 1 has name %q, want %q", old.Name, tender.Name)
+	}
+
+	rolledBack, err := f.models.Tenders.RollbackTender(1, tender.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != 3 || rolledBack.Name != tender.Name {
+		t.Errorf("after rollback got version %d, name %q", rolledBack.Version, rolledBack.Name)
+	}
+
+	_, err = f.models.Tenders.RollbackTender(42, tender.Id)
+	if !errors.Is(err, data.ErrTenderVersionNotFound) {
+		t.Errorf("rollback to a missing version: got error %v, want %v", err, data.ErrTenderVersionNotFound)
+	}
+	current, err := f.models.Tenders.GetTenderById(tender.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != 3 {
+		t.Errorf("failed rollback changed the version to %d", current.Version)
+	}
+}
+
+func TestBidApprovalsFollowVersions(t *testing.T) {
+	f := newFixture(t)
+	tender := f.tender(t, f.organization(0), "Published")
+	bid := f.bid(t, tender.Id, f.organization(1), "Published")
+	reviewer := f.member(0, 0)
+
+	if err := f.models.Bids.ApproveDecision(bid.Id, reviewer); err != nil {
+		t.Fatal(err)
+	}
+	approvers, err := f.models.Bids.ApprovalCount(bid.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(approvers, []string{reviewer}) {
+		t.Errorf("got approvers %v, want %v", approvers, []string{reviewer})
+	}
+
+	edited, err := f.models.Bids.EditBid(bid.Id, data.Bid{Description: "Cheaper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Version != 2 {
+		t.Errorf("after edit got version %d, want 2", edited.Version)
+	}
+	approvers, err = f.models.Bids.ApprovalCount(bid.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approvers) != 0 {
+		t.Errorf("approvals of version 1 count for version 2: %v", approvers)
+	}
+
+	versions, err := f.models.Bids.ApprovalsByVersion(bid.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || !versions[0].Current || versions[0].Approvals != 0 || versions[1].Version != 1 || versions[1].Approvals != 1 {
+		t.Errorf("unexpected approvals by version: %+v, %+v", versions[0], versions[len(versions)-1])
+	}
+
+	err = f.models.Bids.RevokeApproval(bid.Id, reviewer)
+	if !errors.Is(err, data.ErrApprovalNotFound) {
+		t.Errorf("revoking an approval of an old version: got error %v, want %v", err, data.ErrApprovalNotFound)
+	}
+
+	rolledBack, err := f.models.Bids.RollbackBid(1, bid.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != 3 || rolledBack.Description != bid.Description {
+		t.Errorf("after rollback got version %d, description %q", rolledBack.Version, rolledBack.Description)
+	}
+	_, err = f.models.Bids.RollbackBid(42, bid.Id)
+	if !errors.Is(err, data.ErrBidVersionNotFound) {
+		t.Errorf("rollback to a missing version: got error %v, want %v", err, data.ErrBidVersionNotFound)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	f := newFixture(t)
+	tender := f.tender(t, f.organization(0), "Published")
+
+	tx, err := f.models.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ChangeTenderStatus(tender.Id, "Closed"); err != nil {
+		t.Fatal(err)
+	}
+	err = tx.InsertAudit(&data.AuditEntry{Actor: "test", Action: "tender-status", SubjectType: "tender", SubjectId: tender.Id, Note: "rolled back"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := f.models.Tenders.GetTenderStatus(tender.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "Published" {
+		t.Errorf("rolled back transaction changed the status to %s", status)
+	}
+	entries, err := f.models.Audit.GetEntries(tender.Id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("rolled back transaction left %d audit entries", len(entries))
+	}
+}
+
+func TestPurgeHistory(t *testing.T) {
+	f := newFixture(t)
+	tender := f.tender(t, f.organization(0), "Created")
+	for i := 0; i < 4; i++ {
+		if _, err := f.models.Tenders.UpdateTender(tender.Id, data.Tender{Description: "Revised"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx, err := f.models.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenders, bids, err := tx.PurgeHistory(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// Versions 1 to 4 are stored and 5 is current; 3 and 4 are kept.
+	if tenders != 2 || bids != 0 {
+		t.Errorf("purged %d tender and %d bid versions, want 2 and 0", tenders, bids)
+	}
+
+	if _, err := f.models.Tenders.RollbackTender(1, tender.Id); !errors.Is(err, data.ErrTenderVersionNotFound) {
+		t.Errorf("rollback to a purged version: got error %v, want %v", err, data.ErrTenderVersionNotFound)
+	}
+	if _, err := f.models.Tenders.RollbackTender(3, tender.Id); err != nil {
+		t.Errorf("rollback to a kept version: %v", err)
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	f := newFixture(t)
+	published := f.tender(t, f.organization(0), "Published")
+	f.tender(t, f.organization(0), "Created")
+	f.tender(t, f.organization(1), "Published")
+	f.bid(t, published.Id, f.organization(1), "Published")
+	f.bid(t, published.Id, f.organization(2), "Canceled")
+
+	tenders, err := f.models.Tenders.ListTenders(data.TenderFilter{OrganizationId: f.organization(0), Status: "Published"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenders) != 1 || tenders[0].Id != published.Id {
+		t.Errorf("got %d tenders, want only %s", len(tenders), published.Id)
+	}
+
+	bids, err := f.models.Bids.ListBids(data.BidFilter{TenderId: published.Id, Status: "Canceled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bids) != 1 || bids[0].AuthorId != f.organization(2) {
+		t.Errorf("got %d canceled bids", len(bids))
+	}
+
+	memberships, err := f.models.Tenders.GetUserMemberships(f.seeded.Employees[0].Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(memberships.Organizations) != 1 || memberships.Organizations[0].OrganizationId != f.organization(0) {
+		t.Errorf("unexpected memberships %+v", memberships.Organizations)
+	}
+}
+
+func TestTenderImports(t *testing.T) {
+	f := newFixture(t)
+	tender := f.tender(t, f.organization(0), "Created")
+
+	tx, err := f.models.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetImportedTender(f.organization(0), "ext-1"); !errors.Is(err, data.ErrImportNotFound) {
+		t.Errorf("got error %v, want %v", err, data.ErrImportNotFound)
+	}
+	if err := tx.InsertTenderImport(f.organization(0), "ext-1", tender.Id); err != nil {
+		t.Fatal(err)
+	}
+	tenderId, err := tx.GetImportedTender(f.organization(0), "ext-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenderId != tender.Id {
+		t.Errorf("got tender %s, want %s", tenderId, tender.Id)
+	}
+	if err := tx.InsertTenderImport(f.organization(0), "ext-1", tender.Id); !errors.Is(err, data.ErrImportExists) {
+		t.Errorf("importing the same external id again: got error %v, want %v", err, data.ErrImportExists)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	models := data.NewModels(pgtest.Open(t))
+	if err := seed.Prepare(models); err != nil {
+		t.Fatal(err)
+	}
+	keys := models.Idempotency
+
+	claimed, err := keys.Claim("user:alice", "key", "fingerprint", time.Hour)
+	if err != nil || !claimed {
+		t.Fatalf("first claim: got %v, %v", claimed, err)
+	}
+	claimed, err = keys.Claim("user:alice", "key", "fingerprint", time.Hour)
+	if err != nil || claimed {
+		t.Fatalf("second claim: got %v, %v", claimed, err)
+	}
+
+	// The same key sent by someone else is a separate request.
+	claimed, err = keys.Claim("user:bob", "key", "other", time.Hour)
+	if err != nil || !claimed {
+		t.Fatalf("claim in another scope: got %v, %v", claimed, err)
+	}
+
+	if err := keys.Complete("user:alice", "key", 200, "application/json", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := keys.Get("user:alice", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != 200 || string(saved.Body) != `{}` {
+		t.Errorf("got status %d and body %q", saved.Status, saved.Body)
+	}
+
+	if err := keys.Release("user:alice", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.Get("user:alice", "key"); !errors.Is(err, data.ErrIdempotencyKeyNotFound) {
+		t.Errorf("got error %v, want %v", err, data.ErrIdempotencyKeyNotFound)
+	}
+
+	claimed, err = keys.Claim("user:alice", "expired", "fingerprint", -time.Second)
+	if err != nil || !claimed {
+		t.Fatalf("claim: got %v, %v", claimed, err)
+	}
+	claimed, err = keys.Claim("user:alice", "expired", "other", time.Hour)
+	if err != nil || !claimed {
+		t.Errorf("claiming an expired key: got %v, %v", claimed, err)
+	}
+}
@@ -0,0 +1,195 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvitationNotFound = errors.New("invitation does not exist")
+	ErrInvitationExists   = errors.New("invitation already exists")
+)
+
+// Invitation admits a user or an organization to a tender that is not
+// public. Invitees have to accept it before they can bid.
+type Invitation struct {
+	Id          string `json:"id"`
+	TenderId    string `json:"tenderId"`
+	InviteeType string `json:"inviteeType"`
+	InviteeId   string `json:"inviteeId"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"createdAt"`
+	AcceptedAt  string `json:"acceptedAt,omitempty"`
+}
+
+type InvitationModel struct {
+	DB *sql.DB
+}
+
+const invitationColumns = `id, tender_id, invitee_type, invitee_id, status, created_at, accepted_at`
+
+func scanInvitation(scan func(dest ...any) error) (*Invitation, error) {
+	var invitation Invitation
+	var acceptedAt sql.NullTime
+
+	err := scan(&invitation.Id, &invitation.TenderId, &invitation.InviteeType, &invitation.InviteeId,
+		&invitation.Status, &invitation.CreatedAt, &acceptedAt)
+	if err != nil {
+		return nil, err
+	}
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = acceptedAt.Time.Format(time.RFC3339)
+	}
+	return &invitation, nil
+}
+
+func (m InvitationModel) queryInvitations(query string, args ...any) ([]*Invitation, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (m InvitationModel) InsertInvitation(invitation *Invitation) error {
+	query := `
+		INSERT INTO tender_invitations (id, tender_id, invitee_type, invitee_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{invitation.Id, invitation.TenderId, invitation.InviteeType, invitation.InviteeId, invitation.Status, invitation.CreatedAt}
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrInvitationExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (m InvitationModel) GetInvitation(invitationId string) (*Invitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM tender_invitations WHERE id=$1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	invitation, err := scanInvitation(m.DB.QueryRowContext(ctx, query, invitationId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (m InvitationModel) GetTenderInvitations(tenderId string) ([]*Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM tender_invitations
+		WHERE tender_id=$1
+		ORDER BY created_at
+	`
+	return m.queryInvitations(query, tenderId)
+}
+
+// GetInviteeInvitations lists invitations addressed to any of inviteeIds.
+func (m InvitationModel) GetInviteeInvitations(inviteeIds []string) ([]*Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM tender_invitations
+		WHERE invitee_id::text = ANY($1)
+		ORDER BY created_at
+	`
+	return m.queryInvitations(query, pq.Array(inviteeIds))
+}
+
+func (m InvitationModel) AcceptInvitation(invitationId string) (*Invitation, error) {
+	query := `
+		UPDATE tender_invitations SET status='Accepted', accepted_at=coalesce(accepted_at, now())
+		WHERE id=$1
+		RETURNING ` + invitationColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	invitation, err := scanInvitation(m.DB.QueryRowContext(ctx, query, invitationId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (m InvitationModel) DeleteInvitation(tenderId, invitationId string) error {
+	query := `
+		DELETE FROM tender_invitations WHERE id=$1 AND tender_id=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, invitationId, tenderId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// IsInvited reports whether any of inviteeIds has an invitation to the
+// tender, accepted or, unless onlyAccepted is set, still pending.
+func (m InvitationModel) IsInvited(tenderId string, inviteeIds []string, onlyAccepted bool) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM tender_invitations
+			WHERE tender_id=$1 AND invitee_id::text = ANY($2) AND (status='Accepted' OR NOT $3)
+		)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var invited bool
+	err := m.DB.QueryRowContext(ctx, query, tenderId, pq.Array(inviteeIds), onlyAccepted).Scan(&invited)
+	return invited, err
+}
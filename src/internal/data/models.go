@@ -1,11 +1,20 @@
 package data
 
-import "database/sql"
+import (
+	"database/sql"
+	"strings"
+)
 
 type Models struct {
-	Tenders TenderModel
-	Bids    BidModel
-	Tables  TableModel
+	Tenders      TenderModel
+	Bids         BidModel
+	Templates    TemplateModel
+	ServiceTypes ServiceTypeModel
+	Invitations  InvitationModel
+	Suppliers    QualificationModel
+	Idempotency  IdempotencyModel
+	Audit        AuditModel
+	Tables       TableModel
 }
 
 func NewModels(db *sql.DB) Models {
@@ -16,8 +25,36 @@ func NewModels(db *sql.DB) Models {
 		Bids: BidModel{
 			DB: db,
 		},
+		Templates: TemplateModel{
+			DB: db,
+		},
+		ServiceTypes: ServiceTypeModel{
+			DB: db,
+		},
+		Invitations: InvitationModel{
+			DB: db,
+		},
+		Suppliers: QualificationModel{
+			DB: db,
+		},
+		Idempotency: IdempotencyModel{
+			DB: db,
+		},
+		Audit: AuditModel{
+			DB: db,
+		},
 		Tables: TableModel{
 			DB: db,
 		},
 	}
 }
+
+// prefixColumns qualifies each of the comma separated columns with alias,
+// for joins that select a list such as bidColumns.
+func prefixColumns(alias, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = alias + "." + name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// The employee, organization and organization_responsible tables belong to
+// the platform the service runs in and are not created by the migrations.
+// They are only created here for local development and tests.
+
+type Employee struct {
+	Id        string `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+type Organization struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+}
+
+// OrganizationTypes are the values of the platform's organization_type.
+var OrganizationTypes = []string{"IE", "LLC", "JSC"}
+
+func platformSchema() []string {
+	return []string{
+		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+		`
+	CREATE TABLE IF NOT EXISTS employee
+	(
+		id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+		username   varchar(50) UNIQUE NOT NULL,
+		first_name varchar(50),
+		last_name  varchar(50),
+		created_at timestamp DEFAULT current_timestamp,
+		updated_at timestamp DEFAULT current_timestamp
+	)
+	`,
+		`
+	DO $$ BEGIN
+		CREATE TYPE organization_type AS ENUM ('IE', 'LLC', 'JSC');
+	EXCEPTION
+		WHEN duplicate_object THEN NULL;
+	END $$
+	`,
+		`
+	CREATE TABLE IF NOT EXISTS organization
+	(
+		id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+		name        varchar(100) NOT NULL,
+		description text,
+		type        organization_type,
+		created_at  timestamp DEFAULT current_timestamp,
+		updated_at  timestamp DEFAULT current_timestamp
+	)
+	`,
+		`
+	CREATE TABLE IF NOT EXISTS organization_responsible
+	(
+		id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+		organization_id uuid REFERENCES organization (id) ON DELETE CASCADE,
+		user_id         uuid REFERENCES employee (id) ON DELETE CASCADE
+	)
+	`,
+	}
+}
+
+// CreatePlatformTables creates the platform tables the migrations refer to,
+// if they do not exist yet. It must run before CreateTables on an empty
+// database.
+func (m *TableModel) CreatePlatformTables() error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('platform_tables'))`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, query := range platformSchema() {
+		if _, err := tx.Exec(query); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m TenderModel) InsertEmployee(employee *Employee) error {
+	return insertEmployee(m.DB, employee)
+}
+
+func insertEmployee(q querier, employee *Employee) error {
+	query := `
+		INSERT INTO employee (id, username, first_name, last_name)
+		VALUES ($1, $2, $3, $4)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := q.ExecContext(ctx, query, employee.Id, employee.Username, employee.FirstName, employee.LastName)
+	return err
+}
+
+func (m TenderModel) InsertOrganization(organization *Organization) error {
+	return insertOrganization(m.DB, organization)
+}
+
+func insertOrganization(q querier, organization *Organization) error {
+	query := `
+		INSERT INTO organization (id, name, description, type)
+		VALUES ($1, $2, $3, $4)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := q.ExecContext(ctx, query, organization.Id, organization.Name, organization.Description, organization.Type)
+	return err
+}
+
+// AddResponsible makes the user responsible for the organization.
+func (m TenderModel) AddResponsible(organizationId, userId string) error {
+	return addResponsible(m.DB, organizationId, userId)
+}
+
+func addResponsible(q querier, organizationId, userId string) error {
+	query := `
+		INSERT INTO organization_responsible (organization_id, user_id)
+		VALUES ($1, $2)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := q.ExecContext(ctx, query, organizationId, userId)
+	return err
+}
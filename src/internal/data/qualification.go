@@ -0,0 +1,306 @@
+package data
+
+import (
+	"avitotask/internal/validator"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSupplierEntryNotFound = errors.New("supplier list entry does not exist")
+	ErrSupplierEntryExists   = errors.New("supplier is already on this list")
+	ErrRequirementNotFound   = errors.New("qualification is not required for this service type")
+)
+
+const (
+	SupplierAllowList = "allow"
+	SupplierBlockList = "block"
+)
+
+// SupplierEntry puts a user or an organization on the allowlist or the
+// blocklist of an organization. Entries without ExpiresAt never expire.
+type SupplierEntry struct {
+	Id             string `json:"id"`
+	OrganizationId string `json:"organizationId"`
+	List           string `json:"list"`
+	SubjectType    string `json:"subjectType"`
+	SubjectId      string `json:"subjectId"`
+	Reason         string `json:"reason"`
+	ExpiresAt      string `json:"expiresAt,omitempty"`
+	Expired        bool   `json:"expired"`
+	CreatedAt      string `json:"createdAt"`
+}
+
+func SupplierEntryRules() validator.FieldRules {
+	return validator.FieldRules{
+		"list":        {validator.Required(), validator.OneOf(SupplierAllowList, SupplierBlockList)},
+		"subjectType": {validator.Required(), validator.OneOf("User", "Organization")},
+		"subjectId":   {validator.Required(), validator.UUID()},
+		"reason":      {validator.Required(), validator.MaxRunes(500)},
+		"expiresAt":   {validator.RFC3339()},
+	}
+}
+
+// SupplierCheck is the outcome of the qualification rules of a tender's
+// organization for a set of bidder ids.
+type SupplierCheck struct {
+	Blocked   bool
+	Required  bool
+	Qualified bool
+	Expired   bool
+}
+
+type QualificationModel struct {
+	DB *sql.DB
+}
+
+const supplierEntryColumns = `id, organization_id, list, subject_type, subject_id, reason, expires_at, coalesce(expires_at <= now(), false), created_at`
+
+func scanSupplierEntry(scan func(dest ...any) error) (*SupplierEntry, error) {
+	var entry SupplierEntry
+	var expiresAt sql.NullTime
+
+	err := scan(&entry.Id, &entry.OrganizationId, &entry.List, &entry.SubjectType, &entry.SubjectId,
+		&entry.Reason, &expiresAt, &entry.Expired, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		entry.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+	}
+	return &entry, nil
+}
+
+func (m QualificationModel) InsertEntry(entry *SupplierEntry) error {
+	query := `
+		INSERT INTO supplier_lists (id, organization_id, list, subject_type, subject_id, reason, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::timestamptz, $8)
+		RETURNING ` + supplierEntryColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{entry.Id, entry.OrganizationId, entry.List, entry.SubjectType, entry.SubjectId, entry.Reason, entry.ExpiresAt, entry.CreatedAt}
+
+	inserted, err := scanSupplierEntry(m.DB.QueryRowContext(ctx, query, args...).Scan)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrSupplierEntryExists
+		}
+		return err
+	}
+	*entry = *inserted
+	return nil
+}
+
+func (m QualificationModel) GetEntry(entryId string) (*SupplierEntry, error) {
+	query := `SELECT ` + supplierEntryColumns + ` FROM supplier_lists WHERE id=$1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entry, err := scanSupplierEntry(m.DB.QueryRowContext(ctx, query, entryId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSupplierEntryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetEntries lists the organization's entries, optionally only one list.
+// Expired entries are left out unless includeExpired is set.
+func (m QualificationModel) GetEntries(limit, offset int32, organizationId, list string, includeExpired bool) ([]*SupplierEntry, error) {
+	query := `
+		SELECT ` + supplierEntryColumns + `
+		FROM supplier_lists
+		WHERE organization_id=$1 AND (list=$2 OR $2='')
+			AND ($3 OR expires_at IS NULL OR expires_at > now())
+		ORDER BY created_at
+		LIMIT NULLIF($4, 0) OFFSET $5
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, organizationId, list, includeExpired, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	entries := []*SupplierEntry{}
+	for rows.Next() {
+		entry, err := scanSupplierEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UpdateEntry overwrites the reason and the expiry when they are not empty.
+func (m QualificationModel) UpdateEntry(entryId, reason, expiresAt string) (*SupplierEntry, error) {
+	query := `
+		UPDATE supplier_lists SET reason=coalesce(NULLIF($1, ''), reason),
+			expires_at=coalesce(NULLIF($2, '')::timestamptz, expires_at)
+		WHERE id=$3
+		RETURNING ` + supplierEntryColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entry, err := scanSupplierEntry(m.DB.QueryRowContext(ctx, query, reason, expiresAt, entryId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSupplierEntryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (m QualificationModel) DeleteEntry(entryId string) error {
+	query := `
+		DELETE FROM supplier_lists WHERE id=$1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, entryId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSupplierEntryNotFound
+	}
+	return nil
+}
+
+// GetRequirements lists the service types for which the organization only
+// accepts bids from allowlisted suppliers.
+func (m QualificationModel) GetRequirements(organizationId string) ([]string, error) {
+	query := `
+		SELECT service_type FROM qualification_requirements
+		WHERE organization_id=$1
+		ORDER BY service_type
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, organizationId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	serviceTypes := []string{}
+	for rows.Next() {
+		var serviceType string
+		if err := rows.Scan(&serviceType); err != nil {
+			return nil, err
+		}
+		serviceTypes = append(serviceTypes, serviceType)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return serviceTypes, nil
+}
+
+func (m QualificationModel) AddRequirement(organizationId, serviceType string) error {
+	query := `
+		INSERT INTO qualification_requirements (organization_id, service_type)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, organizationId, serviceType)
+	return err
+}
+
+func (m QualificationModel) DeleteRequirement(organizationId, serviceType string) error {
+	query := `
+		DELETE FROM qualification_requirements WHERE organization_id=$1 AND service_type=$2
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, organizationId, serviceType)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRequirementNotFound
+	}
+	return nil
+}
+
+// CheckSupplier applies the lists of organizationId to bidderIds for a tender
+// of serviceType. A requirement on a category covers its subcategories.
+func (m QualificationModel) CheckSupplier(organizationId, serviceType string, bidderIds []string) (SupplierCheck, error) {
+	query := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM supplier_lists
+				WHERE organization_id=$1 AND list='block' AND subject_id::text = ANY($3)
+					AND (expires_at IS NULL OR expires_at > now())
+			),
+			EXISTS (
+				SELECT 1 FROM qualification_requirements
+				WHERE organization_id=$1
+					AND (service_type=$2 OR service_type=(SELECT parent_code FROM service_types WHERE code=$2))
+			),
+			EXISTS (
+				SELECT 1 FROM supplier_lists
+				WHERE organization_id=$1 AND list='allow' AND subject_id::text = ANY($3)
+					AND (expires_at IS NULL OR expires_at > now())
+			),
+			EXISTS (
+				SELECT 1 FROM supplier_lists
+				WHERE organization_id=$1 AND list='allow' AND subject_id::text = ANY($3)
+					AND expires_at <= now()
+			)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var check SupplierCheck
+	err := m.DB.QueryRowContext(ctx, query, organizationId, serviceType, pq.Array(bidderIds)).Scan(
+		&check.Blocked, &check.Required, &check.Qualified, &check.Expired)
+	return check, err
+}
@@ -0,0 +1,285 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTable serves every query against one table. It answers with the
+// columns the query names in its select list or RETURNING clause, and
+// expands * to all columns of the table, so queries that rely on column
+// order break the same way they would after a migration.
+type fakeTable struct {
+	name    string
+	columns []string
+	rows    []map[string]driver.Value
+}
+
+func (t *fakeTable) Connect(context.Context) (driver.Conn, error) { return fakeConn{t}, nil }
+func (t *fakeTable) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ table *fakeTable }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.table, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	table *fakeTable
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	columns, err := s.table.selected(s.query)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{table: s.table, columns: columns}, nil
+}
+
+var returningClause = regexp.MustCompile(`(?s)RETURNING\s+(.+)$`)
+
+func (t *fakeTable) selected(query string) ([]string, error) {
+	var list string
+	if m := returningClause.FindStringSubmatch(query); m != nil {
+		list = m[1]
+	} else {
+		from := regexp.MustCompile(`\s+FROM\s+` + t.name + `\b`).FindStringIndex(query)
+		if from == nil {
+			return nil, fmt.Errorf("fake %s: unsupported query %q", t.name, query)
+		}
+		start := strings.LastIndex(query[:from[0]], "SELECT")
+		if start < 0 {
+			return nil, fmt.Errorf("fake %s: unsupported query %q", t.name, query)
+		}
+		list = query[start+len("SELECT") : from[0]]
+	}
+
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.TrimSpace(column)
+		if column == "*" {
+			columns = append(columns, t.columns...)
+			continue
+		}
+		if !containsColumn(t.columns, column) {
+			return nil, fmt.Errorf("fake %s: unknown column %q", t.name, column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeRows struct {
+	table   *fakeTable
+	columns []string
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.table.rows) {
+		return io.EOF
+	}
+	row := r.table.rows[r.next]
+	r.next++
+	for i, column := range r.columns {
+		dest[i] = row[column]
+	}
+	return nil
+}
+
+// withExtraColumn returns columns with a column appended, as a later
+// migration would do.
+func withExtraColumn(columns string) []string {
+	res := strings.Split(columns, ", ")
+	return append(res, "added_by_migration")
+}
+
+var testDeadline = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTenderTable() *fakeTable {
+	return &fakeTable{
+		name:    "tenders",
+		columns: withExtraColumn(tenderColumns),
+		rows: []map[string]driver.Value{{
+			"id":                 "c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10",
+			"name":               "Tender",
+			"description":        "Tender description",
+			"service_type":       "Construction",
+			"status":             "Published",
+			"organization_id":    "2f7a2a5c-1d43-4b8e-9b9b-6e9f1f1f0a01",
+			"visibility":         VisibilityPublic,
+			"version":            int64(3),
+			"created_at":         "2024-05-06T07:08:09Z",
+			"deadline":           testDeadline,
+			"added_by_migration": "extra",
+		}},
+	}
+}
+
+func newBidTable() *fakeTable {
+	return &fakeTable{
+		name:    "bids",
+		columns: withExtraColumn(bidColumns),
+		rows: []map[string]driver.Value{{
+			"id":                 "7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f",
+			"name":               "Bid",
+			"description":        "Bid description",
+			"status":             "Published",
+			"tender_id":          "c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10",
+			"author_type":        "User",
+			"author_id":          "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d",
+			"version":            int64(2),
+			"created_at":         "2024-05-06T07:08:09Z",
+			"added_by_migration": "extra",
+		}},
+	}
+}
+
+func checkTender(t *testing.T, operation string, tender *Tender, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: %v", operation, err)
+	}
+	want := Tender{
+		Id:             "c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10",
+		Name:           "Tender",
+		Description:    "Tender description",
+		ServiceType:    "Construction",
+		Status:         "Published",
+		OrganizationId: "2f7a2a5c-1d43-4b8e-9b9b-6e9f1f1f0a01",
+		Visibility:     VisibilityPublic,
+		Version:        3,
+		CreatedAt:      "2024-05-06T07:08:09Z",
+		Deadline:       testDeadline.Format(time.RFC3339),
+	}
+	if *tender != want {
+		t.Errorf("%s: got %+v, want %+v", operation, *tender, want)
+	}
+}
+
+func checkBid(t *testing.T, operation string, bid *Bid, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s: %v", operation, err)
+	}
+	want := Bid{
+		Id:          "7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f",
+		Name:        "Bid",
+		Description: "Bid description",
+		Status:      "Published",
+		TenderId:    "c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10",
+		AuthorType:  "User",
+		AuthorId:    "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d",
+		Version:     2,
+		CreatedAt:   "2024-05-06T07:08:09Z",
+	}
+	if *bid != want {
+		t.Errorf("%s: got %+v, want %+v", operation, *bid, want)
+	}
+}
+
+func TestTenderQueriesWithExtraColumns(t *testing.T) {
+	db := sql.OpenDB(newTenderTable())
+	defer db.Close()
+	m := TenderModel{DB: db}
+
+	tender, err := m.GetTenderById("c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10")
+	checkTender(t, "GetTenderById", tender, err)
+
+	tender, err = m.ChangeTenderStatus("c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10", "Published")
+	checkTender(t, "ChangeTenderStatus", tender, err)
+
+	tender, err = m.ChangeTenderVisibility("c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10", VisibilityPublic)
+	checkTender(t, "ChangeTenderVisibility", tender, err)
+
+	tender, err = m.UpdateTender("c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10", Tender{Name: "Tender"})
+	checkTender(t, "UpdateTender", tender, err)
+
+	tenders, err := m.GetTenders(0, 0, nil, nil)
+	if err != nil || len(tenders) != 1 {
+		t.Fatalf("GetTenders: got %d tenders, err %v", len(tenders), err)
+	}
+	checkTender(t, "GetTenders", tenders[0], nil)
+
+	tenders, err = m.GetMyTenders(0, 0, "2f7a2a5c-1d43-4b8e-9b9b-6e9f1f1f0a01")
+	if err != nil || len(tenders) != 1 {
+		t.Fatalf("GetMyTenders: got %d tenders, err %v", len(tenders), err)
+	}
+	checkTender(t, "GetMyTenders", tenders[0], nil)
+}
+
+func TestBidQueriesWithExtraColumns(t *testing.T) {
+	db := sql.OpenDB(newBidTable())
+	defer db.Close()
+	m := BidModel{DB: db}
+
+	bid, err := m.GetBidById("7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f")
+	checkBid(t, "GetBidById", bid, err)
+
+	bid, err = m.ChangeBidStatus("7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f", "Published")
+	checkBid(t, "ChangeBidStatus", bid, err)
+
+	bid, err = m.RejectDecision("7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f")
+	checkBid(t, "RejectDecision", bid, err)
+
+	bid, err = m.EditBid("7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f", Bid{Name: "Bid"})
+	checkBid(t, "EditBid", bid, err)
+
+	bids, err := m.GetMyBids(10, 0, "", "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d")
+	if err != nil || len(bids) != 1 {
+		t.Fatalf("GetMyBids: got %d bids, err %v", len(bids), err)
+	}
+	checkBid(t, "GetMyBids", bids[0], nil)
+
+	bids, err = m.GetBidsByTenderId(10, 0, "c0b3a5b4-3c2e-4c9e-8f5e-6f1d1e3a7b10")
+	if err != nil || len(bids) != 1 {
+		t.Fatalf("GetBidsByTenderId: got %d bids, err %v", len(bids), err)
+	}
+	checkBid(t, "GetBidsByTenderId", bids[0], nil)
+}
+
+// TestSelectStarBreaksWithExtraColumns guards the fake table itself: a query
+// that relies on column order must fail once a column is added.
+func TestSelectStarBreaksWithExtraColumns(t *testing.T) {
+	db := sql.OpenDB(newBidTable())
+	defer db.Close()
+
+	_, err := scanBid(db.QueryRow(`SELECT * FROM bids WHERE id=$1`, "7d9f1c1e-0b52-4f7e-a0a8-8a2b3c4d5e6f").Scan)
+	if err == nil {
+		t.Fatal("scanning SELECT * succeeded despite the extra column")
+	}
+}
@@ -0,0 +1,294 @@
+package data
+
+import (
+	"avitotask/internal/validator"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrServiceTypeNotFound = errors.New("service type does not exist")
+	ErrServiceTypeExists   = errors.New("service type already exists")
+	ErrServiceTypeInUse    = errors.New("service type is used by tenders, templates or subcategories")
+	ErrServiceTypeParent   = errors.New("parent must be an existing top-level category")
+)
+
+// ServiceType is an entry of the service type catalogue. Categories have no
+// parent; subcategories point to a category. Tenders store the code.
+type ServiceType struct {
+	Code       string            `json:"code"`
+	ParentCode string            `json:"parentCode,omitempty"`
+	Name       string            `json:"name"`
+	Names      map[string]string `json:"names"`
+	Active     bool              `json:"active"`
+}
+
+func ServiceTypeRules() validator.FieldRules {
+	return validator.FieldRules{
+		"code":       {validator.Required(), validator.MaxRunes(100)},
+		"parentCode": {validator.MaxRunes(100)},
+	}
+}
+
+type ServiceTypeModel struct {
+	DB *sql.DB
+}
+
+func scanServiceType(scan func(dest ...any) error) (*ServiceType, error) {
+	var serviceType ServiceType
+	var parentCode sql.NullString
+	var names []byte
+
+	err := scan(&serviceType.Code, &parentCode, &names, &serviceType.Active)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceType.ParentCode = parentCode.String
+	if err := json.Unmarshal(names, &serviceType.Names); err != nil {
+		return nil, err
+	}
+	return &serviceType, nil
+}
+
+func (m ServiceTypeModel) GetServiceTypes(includeInactive bool) ([]*ServiceType, error) {
+	query := `
+		SELECT code, parent_code, names, active
+		FROM service_types
+		WHERE active OR $1
+		ORDER BY coalesce(parent_code, code), parent_code IS NOT NULL, code
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	serviceTypes := []*ServiceType{}
+	for rows.Next() {
+		serviceType, err := scanServiceType(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		serviceTypes = append(serviceTypes, serviceType)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return serviceTypes, nil
+}
+
+func (m ServiceTypeModel) GetServiceType(code string) (*ServiceType, error) {
+	query := `
+		SELECT code, parent_code, names, active
+		FROM service_types
+		WHERE code=$1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	serviceType, err := scanServiceType(m.DB.QueryRowContext(ctx, query, code).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServiceTypeNotFound
+		}
+		return nil, err
+	}
+	return serviceType, nil
+}
+
+// UnknownServiceTypes returns the codes that are not in the catalogue.
+func (m ServiceTypeModel) UnknownServiceTypes(codes []string) ([]string, error) {
+	query := `
+		SELECT code FROM unnest($1::text[]) AS requested(code)
+		WHERE NOT EXISTS (SELECT 1 FROM service_types s WHERE s.code = requested.code)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	unknown := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		unknown = append(unknown, code)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return unknown, nil
+}
+
+// checkParent makes sure the catalogue stays two levels deep: a parent must
+// be a top-level category, and a category with subcategories cannot become
+// a subcategory itself.
+func checkParent(ctx context.Context, tx *sql.Tx, code, parentCode string) error {
+	if parentCode == "" {
+		return nil
+	}
+	if parentCode == code {
+		return ErrServiceTypeParent
+	}
+
+	var isTopLevel bool
+	err := tx.QueryRowContext(ctx, `SELECT parent_code IS NULL FROM service_types WHERE code=$1`, parentCode).Scan(&isTopLevel)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrServiceTypeParent
+		}
+		return err
+	}
+	if !isTopLevel {
+		return ErrServiceTypeParent
+	}
+
+	var hasChildren bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM service_types WHERE parent_code=$1)`, code).Scan(&hasChildren)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrServiceTypeParent
+	}
+	return nil
+}
+
+func (m ServiceTypeModel) InsertServiceType(serviceType *ServiceType) error {
+	query := `
+		INSERT INTO service_types (code, parent_code, names, active)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	names, err := json.Marshal(serviceType.Names)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = checkParent(ctx, tx, serviceType.Code, serviceType.ParentCode); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, serviceType.Code, serviceType.ParentCode, names, serviceType.Active)
+	if err != nil {
+		tx.Rollback()
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrServiceTypeExists
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateServiceType replaces the parent, names and active flag of an entry.
+func (m ServiceTypeModel) UpdateServiceType(serviceType *ServiceType) error {
+	query := `
+		UPDATE service_types SET parent_code=NULLIF($2, ''), names=$3, active=$4
+		WHERE code=$1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	names, err := json.Marshal(serviceType.Names)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err = checkParent(ctx, tx, serviceType.Code, serviceType.ParentCode); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, serviceType.Code, serviceType.ParentCode, names, serviceType.Active)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if rows == 0 {
+		tx.Rollback()
+		return ErrServiceTypeNotFound
+	}
+
+	return tx.Commit()
+}
+
+// DeleteServiceType removes an unused entry. Entries referenced by tenders,
+// templates, qualification requirements or subcategories can only be
+// deactivated.
+func (m ServiceTypeModel) DeleteServiceType(code string) error {
+	query := `
+		DELETE FROM service_types s
+		WHERE s.code=$1
+			AND NOT EXISTS (SELECT 1 FROM tenders WHERE service_type = s.code)
+			AND NOT EXISTS (SELECT 1 FROM tender_templates WHERE service_type = s.code)
+			AND NOT EXISTS (SELECT 1 FROM qualification_requirements WHERE service_type = s.code)
+			AND NOT EXISTS (SELECT 1 FROM service_types c WHERE c.parent_code = s.code)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if _, err := m.GetServiceType(code); err != nil {
+			return err
+		}
+		return ErrServiceTypeInUse
+	}
+	return nil
+}
@@ -1,12 +1,36 @@
 package data
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
 
 type TableModel struct {
 	DB *sql.DB
 }
 
-func (m *TableModel) CreateTables() error {
+var migrations = [][]string{
+	initialSchema(),
+	tenderDeadlines(),
+	approvalVersions(),
+	tenderTemplates(),
+	serviceTypeCatalogue(),
+	tenderVisibility(),
+	supplierQualification(),
+	tenderImports(),
+	idempotencyKeys(),
+	adminAudit(),
+	idempotencyKeyScopes(),
+	approvalUniqueness(),
+}
+
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+func initialSchema() []string {
 	bidsQuery :=
 		`
 	create table if not exists bids
@@ -75,41 +99,273 @@ func (m *TableModel) CreateTables() error {
 	)
 	`
 
-	tx, err := m.DB.Begin()
-	if err != nil {
-		return err
+	return []string{bidsQuery, bidsHistoryQuery, tendersQuery, tendersHistoryQuery, bidsApprovalsQuery}
+}
+
+func tenderDeadlines() []string {
+	return []string{
+		`ALTER TABLE tenders ADD COLUMN IF NOT EXISTS deadline timestamp with time zone`,
+	}
+}
+
+// approvalVersions ties approvals to the bid version they were given for.
+// Existing rows do not record which content they approved, since edits and
+// rollbacks kept them, so they are dropped instead of counting for the
+// current version.
+func approvalVersions() []string {
+	return []string{
+		`ALTER TABLE bids_approvals ADD COLUMN IF NOT EXISTS bid_version integer`,
+		`ALTER TABLE bids_approvals ADD COLUMN IF NOT EXISTS created_at timestamp with time zone NOT NULL DEFAULT now()`,
+		`DELETE FROM bids_approvals WHERE bid_version IS NULL`,
+		`ALTER TABLE bids_approvals ALTER COLUMN bid_version SET NOT NULL`,
 	}
+}
 
-	_, err = tx.Exec(bidsQuery)
-	if err != nil {
-		tx.Rollback()
-		return err
+func tenderTemplates() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS tender_templates
+	(
+		id              uuid                     NOT NULL PRIMARY KEY,
+		name            varchar(100)             NOT NULL,
+		description     varchar(500)             NOT NULL,
+		service_type    varchar(100)             NOT NULL,
+		organization_id uuid                     NOT NULL
+			REFERENCES organization ON DELETE CASCADE,
+		created_at      timestamp with time zone NOT NULL,
+		updated_at      timestamp with time zone NOT NULL
+	)
+	`,
+		`CREATE INDEX IF NOT EXISTS tender_templates_organization_id_idx ON tender_templates (organization_id)`,
+	}
+}
+
+// serviceTypeCatalogue moves service types from code into a table, seeded
+// with the types that used to be hard-coded.
+func serviceTypeCatalogue() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS service_types
+	(
+		code        varchar(100) NOT NULL PRIMARY KEY,
+		parent_code varchar(100)
+			REFERENCES service_types,
+		names       jsonb        NOT NULL DEFAULT '{}',
+		active      boolean      NOT NULL DEFAULT true
+	)
+	`,
+		`
+	INSERT INTO service_types (code, names) VALUES
+		('Construction', '{"en": "Construction", "ru": "Строительство"}'),
+		('Delivery', '{"en": "Delivery", "ru": "Доставка"}'),
+		('Manufacture', '{"en": "Manufacture", "ru": "Производство"}')
+	ON CONFLICT (code) DO NOTHING
+	`,
+	}
+}
+
+func tenderVisibility() []string {
+	return []string{
+		`ALTER TABLE tenders ADD COLUMN IF NOT EXISTS visibility varchar(20) NOT NULL DEFAULT 'public'`,
+		`
+	CREATE TABLE IF NOT EXISTS tender_invitations
+	(
+		id           uuid                     NOT NULL PRIMARY KEY,
+		tender_id    uuid                     NOT NULL
+			REFERENCES tenders ON DELETE CASCADE,
+		invitee_type varchar(50)              NOT NULL,
+		invitee_id   uuid                     NOT NULL,
+		status       varchar(20)              NOT NULL DEFAULT 'Pending',
+		created_at   timestamp with time zone NOT NULL,
+		accepted_at  timestamp with time zone,
+		UNIQUE (tender_id, invitee_id)
+	)
+	`,
+		`CREATE INDEX IF NOT EXISTS tender_invitations_invitee_id_idx ON tender_invitations (invitee_id)`,
+	}
+}
+
+func supplierQualification() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS supplier_lists
+	(
+		id              uuid                     NOT NULL PRIMARY KEY,
+		organization_id uuid                     NOT NULL
+			REFERENCES organization ON DELETE CASCADE,
+		list            varchar(10)              NOT NULL,
+		subject_type    varchar(50)              NOT NULL,
+		subject_id      uuid                     NOT NULL,
+		reason          text                     NOT NULL,
+		expires_at      timestamp with time zone,
+		created_at      timestamp with time zone NOT NULL,
+		UNIQUE (organization_id, list, subject_id)
+	)
+	`,
+		`CREATE INDEX IF NOT EXISTS supplier_lists_subject_id_idx ON supplier_lists (subject_id)`,
+		`
+	CREATE TABLE IF NOT EXISTS qualification_requirements
+	(
+		organization_id uuid         NOT NULL
+			REFERENCES organization ON DELETE CASCADE,
+		service_type    varchar(100) NOT NULL
+			REFERENCES service_types,
+		PRIMARY KEY (organization_id, service_type)
+	)
+	`,
+	}
+}
+
+func tenderImports() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS tender_imports
+	(
+		organization_id uuid                     NOT NULL
+			REFERENCES organization ON DELETE CASCADE,
+		external_id     varchar(100)             NOT NULL,
+		tender_id       uuid                     NOT NULL
+			REFERENCES tenders ON DELETE CASCADE,
+		created_at      timestamp with time zone NOT NULL DEFAULT now(),
+		PRIMARY KEY (organization_id, external_id)
+	)
+	`,
+	}
+}
+
+func idempotencyKeys() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS idempotency_keys
+	(
+		key          varchar(255)             NOT NULL PRIMARY KEY,
+		fingerprint  varchar(64)              NOT NULL,
+		status       integer,
+		content_type varchar(255),
+		body         bytea,
+		created_at   timestamp with time zone NOT NULL DEFAULT now(),
+		expires_at   timestamp with time zone NOT NULL
+	)
+	`,
+		`CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at)`,
+	}
+}
+
+func adminAudit() []string {
+	return []string{
+		`
+	CREATE TABLE IF NOT EXISTS admin_audit
+	(
+		id           uuid                     NOT NULL PRIMARY KEY,
+		actor        varchar(100)             NOT NULL,
+		action       varchar(50)              NOT NULL,
+		subject_type varchar(50)              NOT NULL,
+		subject_id   uuid                     NOT NULL,
+		note         varchar(500)             NOT NULL,
+		created_at   timestamp with time zone NOT NULL DEFAULT now()
+	)
+	`,
+		`CREATE INDEX IF NOT EXISTS admin_audit_subject_idx ON admin_audit (subject_id, created_at)`,
+	}
+}
+
+// idempotencyKeyScopes makes keys unique per caller rather than globally.
+// Keys stored before have an empty scope and simply expire.
+func idempotencyKeyScopes() []string {
+	return []string{
+		`ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS scope varchar(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey`,
+		`ALTER TABLE idempotency_keys ADD PRIMARY KEY (scope, key)`,
+	}
+}
+
+// approvalUniqueness lets every user approve a bid version only once.
+// Duplicates left by concurrent approvals are dropped before the index is
+// built.
+func approvalUniqueness() []string {
+	return []string{
+		`
+	DELETE FROM bids_approvals a
+	USING bids_approvals b
+	WHERE a.bid_id = b.bid_id
+		AND a.user_id = b.user_id
+		AND a.bid_version = b.bid_version
+		AND a.ctid > b.ctid
+	`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS bids_approvals_unique_idx ON bids_approvals (bid_id, user_id, bid_version)`,
 	}
-	_, err = tx.Exec(bidsHistoryQuery)
+}
+
+func (m *TableModel) CreateTables() error {
+	migrationsQuery :=
+		`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer                  NOT NULL PRIMARY KEY,
+		applied_at timestamp with time zone NOT NULL DEFAULT now()
+	)
+	`
+
+	tx, err := m.DB.Begin()
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	_, err = tx.Exec(tendersQuery)
+
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))`)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	_, err = tx.Exec(tendersHistoryQuery)
+
+	_, err = tx.Exec(migrationsQuery)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	_, err = tx.Exec(bidsApprovalsQuery)
+	var current int
+	err = tx.QueryRow(`SELECT coalesce(max(version), 0) FROM schema_migrations`).Scan(&current)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	for version := current + 1; version <= len(migrations); version++ {
+		for _, query := range migrations[version-1] {
+			_, err = tx.Exec(query)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", version, err)
+			}
+		}
+
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1)`, version)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
 		return err
 	}
 	return nil
+}
 
+func (m TableModel) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+func (m TableModel) CurrentSchemaVersion(ctx context.Context) (int, error) {
+	query := `
+		SELECT coalesce(max(version), 0) FROM schema_migrations
+	`
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var version int
+	err := m.DB.QueryRowContext(ctx, query).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
 }
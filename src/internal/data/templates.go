@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+var ErrTemplateNotFound = errors.New("template does not exist")
+
+// Template is a reusable tender draft owned by an organization.
+type Template struct {
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	ServiceType    string `json:"serviceType"`
+	OrganizationId string `json:"organizationId"`
+	CreatedAt      string `json:"createdAt"`
+	UpdatedAt      string `json:"updatedAt"`
+}
+
+type TemplateModel struct {
+	DB *sql.DB
+}
+
+func (m TemplateModel) InsertTemplate(template *Template) error {
+	query := `
+		INSERT INTO tender_templates (id, name, description, service_type, organization_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{template.Id, template.Name, template.Description, template.ServiceType, template.OrganizationId, template.CreatedAt, template.UpdatedAt}
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (m TemplateModel) GetTemplate(templateId string) (*Template, error) {
+	query := `
+		SELECT id, name, description, service_type, organization_id, created_at, updated_at
+		FROM tender_templates
+		WHERE id=$1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var template Template
+
+	err := m.DB.QueryRowContext(ctx, query, templateId).Scan(
+		&template.Id, &template.Name, &template.Description, &template.ServiceType, &template.OrganizationId, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (m TemplateModel) GetTemplates(limit, offset int32, organizationId string) ([]*Template, error) {
+	query := `
+		SELECT id, name, description, service_type, organization_id, created_at, updated_at
+		FROM tender_templates
+		WHERE organization_id=$1
+		ORDER BY name
+		LIMIT NULLIF($2, 0) OFFSET $3
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, organizationId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	templates := []*Template{}
+	for rows.Next() {
+		var template Template
+
+		err := rows.Scan(
+			&template.Id,
+			&template.Name,
+			&template.Description,
+			&template.ServiceType,
+			&template.OrganizationId,
+			&template.CreatedAt,
+			&template.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, &template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// UpdateTemplate overwrites the non-empty fields of the template.
+func (m TemplateModel) UpdateTemplate(templateId string, changes Template) (*Template, error) {
+	query := `
+		UPDATE tender_templates SET name=coalesce(NULLIF($1,''), name), description=coalesce(NULLIF($2,''), description),
+		service_type=coalesce(NULLIF($3,''), service_type), updated_at=now()
+		WHERE id=$4
+		RETURNING id, name, description, service_type, organization_id, created_at, updated_at
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var template Template
+
+	err := m.DB.QueryRowContext(ctx, query, changes.Name, changes.Description, changes.ServiceType, templateId).Scan(
+		&template.Id, &template.Name, &template.Description, &template.ServiceType, &template.OrganizationId, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (m TemplateModel) DeleteTemplate(templateId string) error {
+	query := `
+		DELETE FROM tender_templates WHERE id=$1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, templateId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
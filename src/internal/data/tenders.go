@@ -1,11 +1,14 @@
 package data
 
 import (
+	"avitotask/internal/validator"
 	"context"
 	"database/sql"
 	"errors"
 	"log"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -23,55 +26,127 @@ type Tender struct {
 	ServiceType    string `json:"serviceType"`
 	Status         string `json:"status"`
 	OrganizationId string `json:"-"`
+	Visibility     string `json:"visibility"`
 	Version        int    `json:"version"`
 	CreatedAt      string `json:"createdAt"`
+	Deadline       string `json:"deadline,omitempty"`
+}
+
+const (
+	VisibilityPublic     = "public"
+	VisibilityInviteOnly = "invite-only"
+	VisibilityPrivate    = "private"
+)
+
+// Visibilities lists the tender visibility modes. Public tenders are open to
+// everyone; invite-only tenders are listed for everyone but only accepted
+// invitees may bid; private tenders are also hidden from everyone else.
+var Visibilities = []string{VisibilityPublic, VisibilityInviteOnly, VisibilityPrivate}
+
+// DeadlinePassed reports whether the tender has a deadline earlier than now.
+func (t Tender) DeadlinePassed(now time.Time) bool {
+	if t.Deadline == "" {
+		return false
+	}
+	deadline, err := time.Parse(time.RFC3339, t.Deadline)
+	return err == nil && deadline.Before(now)
+}
+
+// TenderRules checks the shape of tender fields. Whether the service type is
+// in the catalogue is checked against the database by the caller.
+func TenderRules() validator.FieldRules {
+	return validator.FieldRules{
+		"name":        {validator.Required(), validator.MaxRunes(100)},
+		"description": {validator.Required(), validator.MaxRunes(500)},
+		"serviceType": {validator.Required(), validator.MaxRunes(100)},
+	}
 }
 
 type TenderModel struct {
 	DB *sql.DB
 }
 
-func (m TenderModel) GetTenderById(tenderId string) (*Tender, error) {
-	query := `
-		SELECT * FROM tenders WHERE id=$1
-	`
+// tenderColumns lists the columns scanTender expects, in order. Queries name
+// them explicitly so that new columns do not shift the mapping.
+const tenderColumns = `id, name, description, service_type, status, organization_id, visibility, version, created_at, deadline`
 
+func scanTender(scan func(dest ...any) error) (*Tender, error) {
 	var tender Tender
+	var deadline sql.NullTime
+
+	err := scan(&tender.Id, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status,
+		&tender.OrganizationId, &tender.Visibility, &tender.Version, &tender.CreatedAt, &deadline)
+	if err != nil {
+		return nil, err
+	}
+	if deadline.Valid {
+		tender.Deadline = deadline.Time.Format(time.RFC3339)
+	}
+	return &tender, nil
+}
+
+func scanTenders(rows *sql.Rows) ([]*Tender, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	tenders := []*Tender{}
+	for rows.Next() {
+		tender, err := scanTender(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		tenders = append(tenders, tender)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tenders, nil
+}
+
+func (m TenderModel) GetTenderById(tenderId string) (*Tender, error) {
+	return getTenderById(m.DB, tenderId)
+}
+
+func getTenderById(q querier, tenderId string) (*Tender, error) {
+	query := `SELECT ` + tenderColumns + ` FROM tenders WHERE id=$1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	row := m.DB.QueryRowContext(ctx, query, tenderId)
-	err := row.Scan(&tender.Id, &tender.Name, &tender.CreatedAt, &tender.ServiceType, &tender.Status, &tender.OrganizationId, &tender.Version, &tender.CreatedAt)
-
+	tender, err := scanTender(q.QueryRowContext(ctx, query, tenderId).Scan)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrTenderNotFound
 		}
 		return nil, err
 	}
-	return &tender, nil
+	return tender, nil
 }
 
 func (m TenderModel) ChangeTenderStatus(tenderId string, status string) (*Tender, error) {
+	return changeTenderStatus(m.DB, tenderId, status)
+}
+
+func changeTenderStatus(q querier, tenderId string, status string) (*Tender, error) {
 	changeStatusQuery := `
-		UPDATE tenders SET status=$1 WHERE id=$2 
-		RETURNING id, name, description, status, service_type, version, created_at
-	`
+		UPDATE tenders SET status=$1 WHERE id=$2
+		RETURNING ` + tenderColumns
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	var tender Tender
-
-	err := m.DB.QueryRowContext(ctx, changeStatusQuery, status, tenderId).Scan(
-		&tender.Id, &tender.Name, &tender.Description, &tender.Status, &tender.ServiceType, &tender.Version, &tender.CreatedAt)
+	tender, err := scanTender(q.QueryRowContext(ctx, changeStatusQuery, status, tenderId).Scan)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrTenderNotFound
 		}
 		return nil, err
 	}
-	return &tender, nil
+	return tender, nil
 }
 
 func (m TenderModel) GetTenderStatus(tenderId string) (string, error) {
@@ -137,6 +212,34 @@ func (m TenderModel) GetUserOrganization(userId string) (string, error) {
 
 	return organizationId, nil
 }
+func (m TenderModel) UserExists(userId string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM employee WHERE id=$1)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, userId).Scan(&exists)
+	return exists, err
+}
+
+func (m TenderModel) OrganizationExists(organizationId string) (bool, error) {
+	return organizationExists(m.DB, organizationId)
+}
+
+func organizationExists(q querier, organizationId string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM organization WHERE id=$1)
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := q.QueryRowContext(ctx, query, organizationId).Scan(&exists)
+	return exists, err
+}
+
 func (m TenderModel) GetOrganizationUsers(organizationId string) ([]string, error) {
 	query := `
 		SELECT user_id FROM organization_responsible WHERE organization_id=$1
@@ -173,16 +276,20 @@ func (m TenderModel) GetOrganizationUsers(organizationId string) ([]string, erro
 }
 
 func (m TenderModel) InsertTender(tender *Tender) error {
+	return insertTender(m.DB, tender)
+}
+
+func insertTender(q querier, tender *Tender) error {
 	query := `
-		INSERT INTO tenders (id, name, description, service_type, status, organization_id, version, created_at) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		INSERT INTO tenders (id, name, description, service_type, status, organization_id, visibility, version, created_at, deadline) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, '')::timestamp with time zone) 
 		`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{tender.Id, tender.Name, tender.Description, tender.ServiceType, tender.Status, tender.OrganizationId, tender.Version, tender.CreatedAt}
+	args := []interface{}{tender.Id, tender.Name, tender.Description, tender.ServiceType, tender.Status, tender.OrganizationId, tender.Visibility, tender.Version, tender.CreatedAt, tender.Deadline}
 
-	_, err := m.DB.ExecContext(ctx, query, args...)
+	_, err := q.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -210,63 +317,46 @@ func (m TenderModel) GetTenderOrganization(tenderId string) (string, error) {
 	return organizationId, nil
 }
 
-func (m TenderModel) GetTenders(limit, offset int32, serviceTypes []string) ([]*Tender, error) {
+// GetTenders lists published tenders. When serviceTypes is not empty only
+// tenders of those types are returned; a category also matches all of its
+// subcategories. Private tenders are only listed for their owner and for
+// invitees among viewerIds.
+func (m TenderModel) GetTenders(limit, offset int32, serviceTypes, viewerIds []string) ([]*Tender, error) {
 	query := `
-		SELECT id, name, description, service_type, status, organization_id, version, created_at
+		WITH RECURSIVE selected(code) AS (
+			SELECT code FROM service_types WHERE code = ANY($1)
+			UNION
+			SELECT s.code FROM service_types s JOIN selected ON s.parent_code = selected.code
+		)
+		SELECT ` + tenderColumns + `
 		FROM tenders
-		WHERE ((service_type = $1 OR service_type = $2 OR service_type = $3) 
-		OR ($1='' AND $2='' AND $3=''))
+		WHERE (cardinality($1::text[]) = 0 OR service_type IN (SELECT code FROM selected))
 		AND status='Published'
+		AND (visibility <> 'private'
+			OR organization_id::text = ANY($4)
+			OR EXISTS (
+				SELECT 1 FROM tender_invitations i
+				WHERE i.tender_id = tenders.id AND i.invitee_id::text = ANY($4)
+			))
 		ORDER BY name
-		LIMIT NULLIF($4, 0) OFFSET $5
+		LIMIT NULLIF($2, 0) OFFSET $3
 		`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{serviceTypes[0], serviceTypes[1], serviceTypes[2], limit, offset}
+	args := []any{pq.Array(serviceTypes), limit, offset, pq.Array(viewerIds)}
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	tenders := []*Tender{}
-	for rows.Next() {
-		var tender Tender
-
-		err := rows.Scan(
-			&tender.Id,
-			&tender.Name,
-			&tender.Description,
-			&tender.ServiceType,
-			&tender.Status,
-			&tender.OrganizationId,
-			&tender.Version,
-			&tender.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		tenders = append(tenders, &tender)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return tenders, nil
+	return scanTenders(rows)
 }
 
 func (m TenderModel) GetMyTenders(limit, offset int32, organization_id string) ([]*Tender, error) {
 	query := `
-		SELECT id, name, description, service_type, status, organization_id, version, created_at
+		SELECT ` + tenderColumns + `
 		FROM tenders
 		WHERE (organization_id=$1)
 		ORDER BY name
@@ -282,86 +372,53 @@ func (m TenderModel) GetMyTenders(limit, offset int32, organization_id string) (
 		return nil, err
 	}
 
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Println(err)
-		}
-	}()
-
-	tenders := []*Tender{}
-	for rows.Next() {
-		var tender Tender
-
-		err := rows.Scan(
-			&tender.Id,
-			&tender.Name,
-			&tender.Description,
-			&tender.ServiceType,
-			&tender.Status,
-			&tender.OrganizationId,
-			&tender.Version,
-			&tender.CreatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		tenders = append(tenders, &tender)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return tenders, nil
+	return scanTenders(rows)
 }
 
 func (m *TenderModel) UpdateTender(tenderId string, newTender Tender) (*Tender, error) {
-
-	///MULTIPLE CONTEXTS????
-	updateQuery := `
-		UPDATE tenders SET name=coalesce(NULLIF($1,''), name), description=coalesce(NULLIF($2,''), description), 
-		service_type=coalesce(NULLIF($3,''), service_type),version=$4 WHERE id=$5
-		RETURNING id, name, description, status, service_type, version, created_at
-	`
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	currentTender := Tender{}
 
-	err = tx.QueryRow("SELECT id, name, description, service_type, version FROM tenders WHERE id = $1", tenderId).Scan(
-		&currentTender.Id, &currentTender.Name, &currentTender.Description, &currentTender.ServiceType, &currentTender.Version)
+	tender, err := updateTender(tx, tenderId, newTender)
 	if err != nil {
 		tx.Rollback()
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrTenderNotFound
-		}
 		return nil, err
 	}
 
-	_, err = tx.Exec(`INSERT INTO tenders_history (tender_id, name, description, service_type, version) 
-	VALUES ($1, $2, $3, $4, $5)`, currentTender.Id, currentTender.Name, currentTender.Description, currentTender.ServiceType, currentTender.Version)
-
-	if err != nil {
-		tx.Rollback()
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	row := tx.QueryRow(updateQuery, newTender.Name, newTender.Description, newTender.ServiceType, currentTender.Version+1, tenderId)
+	return tender, nil
+}
 
-	err = row.Scan(&newTender.Id, &newTender.Name, &newTender.Description, &newTender.Status, &newTender.ServiceType, &newTender.Version, &newTender.CreatedAt)
+// updateTender keeps the current version in the history and applies the
+// non-empty fields of newTender as the next one. q must be a transaction.
+func updateTender(q querier, tenderId string, newTender Tender) (*Tender, error) {
+	updateQuery := `
+		UPDATE tenders SET name=coalesce(NULLIF($1,''), name), description=coalesce(NULLIF($2,''), description), 
+		service_type=coalesce(NULLIF($3,''), service_type),version=$4 WHERE id=$5
+		RETURNING ` + tenderColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
+	currentTender, err := scanTender(q.QueryRowContext(ctx, "SELECT "+tenderColumns+" FROM tenders WHERE id = $1", tenderId).Scan)
 	if err != nil {
-		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenderNotFound
+		}
 		return nil, err
 	}
 
-	if err := tx.Commit(); err != nil {
+	_, err = q.ExecContext(ctx, `INSERT INTO tenders_history (tender_id, name, description, service_type, version) 
+	VALUES ($1, $2, $3, $4, $5)`, currentTender.Id, currentTender.Name, currentTender.Description, currentTender.ServiceType, currentTender.Version)
+	if err != nil {
 		return nil, err
 	}
 
-	return &newTender, nil
-
+	return scanTender(q.QueryRowContext(ctx, updateQuery, newTender.Name, newTender.Description, newTender.ServiceType, currentTender.Version+1, tenderId).Scan)
 }
 
 func (m *TenderModel) RollbackTender(targetVersion int, tenderId string) (*Tender, error) {
@@ -375,26 +432,23 @@ func (m *TenderModel) RollbackTender(targetVersion int, tenderId string) (*Tende
 		`
 		UPDATE tenders SET name=$1, description=$2, service_type=$3, version=version+1
 		WHERE id=$4
-		RETURNING id, name, description, status, service_type, version, created_at
-	`
+		RETURNING ` + tenderColumns
+
 	tx, err := m.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
 
-	currentParams := struct {
-		name        string
-		description string
-		serviceType string
-		version     string
-	}{}
-	err = tx.QueryRow("SELECT name, description, service_type, version FROM tenders WHERE id = $1", tenderId).Scan(&currentParams.name, &currentParams.description, &currentParams.serviceType, &currentParams.version)
+	currentTender, err := scanTender(tx.QueryRow("SELECT "+tenderColumns+" FROM tenders WHERE id = $1", tenderId).Scan)
 	if err != nil {
 		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenderNotFound
+		}
 		return nil, err
 	}
 
-	_, err = tx.Exec("INSERT INTO tenders_history (tender_id, name, description, service_type,version) VALUES ($1, $2, $3,$4,$5)", tenderId, currentParams.name, currentParams.description, currentParams.serviceType, currentParams.version)
+	_, err = tx.Exec("INSERT INTO tenders_history (tender_id, name, description, service_type,version) VALUES ($1, $2, $3,$4,$5)", tenderId, currentTender.Name, currentTender.Description, currentTender.ServiceType, currentTender.Version)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -416,10 +470,8 @@ func (m *TenderModel) RollbackTender(targetVersion int, tenderId string) (*Tende
 		return nil, err
 	}
 
-	tender := Tender{}
-
 	row = tx.QueryRow(rollbackTenderQuery, historyParams.name, historyParams.description, historyParams.service_type, tenderId)
-	err = row.Scan(&tender.Id, &tender.Name, &tender.Description, &tender.Status, &tender.ServiceType, &tender.Version, &tender.CreatedAt)
+	tender, err := scanTender(row.Scan)
 
 	if err != nil {
 		tx.Rollback()
@@ -432,6 +484,58 @@ func (m *TenderModel) RollbackTender(targetVersion int, tenderId string) (*Tende
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
-	return &tender, nil
+	return tender, nil
+
+}
+
+func (m TenderModel) ChangeTenderVisibility(tenderId string, visibility string) (*Tender, error) {
+	query := `
+		UPDATE tenders SET visibility=$1 WHERE id=$2
+		RETURNING ` + tenderColumns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tender, err := scanTender(m.DB.QueryRowContext(ctx, query, visibility, tenderId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	return tender, nil
+}
+
+// GetTenderVersion returns the content of a tender as it was at version, or
+// its current content when version is 0. Visibility is not versioned, it is always the
+// current one.
+func (m TenderModel) GetTenderVersion(tenderId string, version int) (*Tender, error) {
+	query := `
+		SELECT id, name, description, service_type, organization_id, visibility, version
+		FROM tenders
+		WHERE id=$1 AND ($2=0 OR version=$2)
+		UNION ALL
+		SELECT t.id, h.name, h.description, h.service_type, t.organization_id, t.visibility, h.version
+		FROM tenders_history h
+		JOIN tenders t ON t.id = h.tender_id
+		WHERE h.tender_id=$1 AND h.version=$2 AND t.version <> $2
+		LIMIT 1
+	`
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var tender Tender
 
+	err := m.DB.QueryRowContext(ctx, query, tenderId, version).Scan(
+		&tender.Id, &tender.Name, &tender.Description, &tender.ServiceType, &tender.OrganizationId, &tender.Visibility, &tender.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if version == 0 {
+				return nil, ErrTenderNotFound
+			}
+			return nil, ErrTenderVersionNotFound
+		}
+		return nil, err
+	}
+	return &tender, nil
 }
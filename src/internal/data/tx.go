@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so the same query code
+// can run on its own or as part of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Tx runs model operations inside one database transaction, for bulk
+// requests, admin commands and the seeder. It must be finished with Commit
+// or Rollback.
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (m Models) Begin() (*Tx, error) {
+	tx, err := m.Tenders.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t *Tx) GetTenderById(tenderId string) (*Tender, error) {
+	return getTenderById(t.tx, tenderId)
+}
+
+func (t *Tx) ChangeTenderStatus(tenderId, status string) (*Tender, error) {
+	return changeTenderStatus(t.tx, tenderId, status)
+}
+
+func (t *Tx) InsertTender(tender *Tender) error {
+	return insertTender(t.tx, tender)
+}
+
+// LockTender reads a tender and locks its row until the transaction ends,
+// so that it cannot change between reading and updating it.
+func (t *Tx) LockTender(tenderId string) (*Tender, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tender, err := scanTender(t.tx.QueryRowContext(ctx, `SELECT `+tenderColumns+` FROM tenders WHERE id=$1 FOR UPDATE`, tenderId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	return tender, nil
+}
+
+// LockBid reads a bid and locks its row until the transaction ends.
+func (t *Tx) LockBid(bidId string) (*Bid, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bid, err := scanBid(t.tx.QueryRowContext(ctx, `SELECT `+bidColumns+` FROM bids WHERE id=$1 FOR UPDATE`, bidId).Scan)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBidNotFound
+		}
+		return nil, err
+	}
+	return bid, nil
+}
+
+func (t *Tx) GetBidById(bidId string) (*Bid, error) {
+	return getBidById(t.tx, bidId)
+}
+
+func (t *Tx) ApprovalCount(bidId string) ([]string, error) {
+	return approvalCount(t.tx, bidId)
+}
+
+func (t *Tx) ApproveDecision(bidId, userId string) error {
+	return approveDecision(t.tx, bidId, userId)
+}
+
+func (t *Tx) RejectDecision(bidId string) (*Bid, error) {
+	return rejectDecision(t.tx, bidId)
+}
+
+func (t *Tx) UpdateTender(tenderId string, newTender Tender) (*Tender, error) {
+	return updateTender(t.tx, tenderId, newTender)
+}
+
+func (t *Tx) InsertBid(bid *Bid) error {
+	return insertBid(t.tx, bid)
+}
+
+func (t *Tx) EditBid(bidId string, newBid Bid) (*Bid, error) {
+	return editBid(t.tx, bidId, newBid)
+}
+
+func (t *Tx) OrganizationExists(organizationId string) (bool, error) {
+	return organizationExists(t.tx, organizationId)
+}
+
+func (t *Tx) InsertOrganization(organization *Organization) error {
+	return insertOrganization(t.tx, organization)
+}
+
+func (t *Tx) InsertEmployee(employee *Employee) error {
+	return insertEmployee(t.tx, employee)
+}
+
+func (t *Tx) AddResponsible(organizationId, userId string) error {
+	return addResponsible(t.tx, organizationId, userId)
+}
@@ -0,0 +1,42 @@
+// Package export writes tabular reports row by row, so that a report of any
+// size is never held in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var Formats = []string{FormatCSV, FormatXLSX}
+
+// Writer receives the header as the first row. Cells are strings, integers
+// or booleans.
+type Writer interface {
+	WriteRow(cells ...any) error
+	// Flush pushes buffered rows to the underlying writer.
+	Flush() error
+	// Close finishes the document. Without it an XLSX report is unreadable.
+	Close() error
+}
+
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSV(w), nil
+	case FormatXLSX:
+		return NewXLSX(w, "Report")
+	}
+	return nil, fmt.Errorf("export: unknown format %q", format)
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
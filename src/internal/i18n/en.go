@@ -0,0 +1,78 @@
+package i18n
+
+var english = Bundle{
+	"VALIDATION_FAILED":                   "request parameters failed validation",
+	"UNAUTHORIZED":                        "username is incorrect or does not exist",
+	"FORBIDDEN":                           "user does not have rights for this action",
+	"RATE_LIMITED":                        "rate limit exceeded",
+	"INTERNAL_ERROR":                      "the server encountered a problem and could not handle request",
+	"USER_NOT_FOUND":                      "username is incorrect or does not exist",
+	"ORGANIZATION_NOT_FOUND":              "user is not responsible for any organization",
+	"NO_RIGHTS":                           "user does not have rights for this action",
+	"TENDER_NOT_FOUND":                    "tender does not exist",
+	"TENDER_VERSION_NOT_FOUND":            "tender or version does not exist",
+	"TENDER_NOT_PUBLISHED":                "trying to bid on tender that is not published",
+	"TENDER_INACTIVE":                     "trying to send decision for inactive tender",
+	"NOT_TENDER_RESPONSIBLE":              "user is not responsible for this tender",
+	"BID_NOT_FOUND":                       "bid does not exist",
+	"BID_OR_TENDER_NOT_FOUND":             "bid or tender does not exist",
+	"BID_VERSION_NOT_FOUND":               "bid version does not exist",
+	"BID_INACTIVE":                        "trying to send decision on inactive bid",
+	"BID_ALREADY_APPROVED":                "user has already approved",
+	"SELF_BID_FORBIDDEN":                  "trying to bid on your own tender",
+	"NOT_BID_RESPONSIBLE":                 "user is not responsible for this bid",
+	"BID_NOT_WITHDRAWABLE":                "only created or published bids can be withdrawn",
+	"BID_NOT_WITHDRAWN":                   "only withdrawn bids can be resubmitted",
+	"BID_WITHDRAWN":                       "bid is withdrawn and can only be resubmitted",
+	"BID_NOT_EDITABLE":                    "only created or published bids can be edited",
+	"TENDER_DEADLINE_PASSED":              "tender deadline has passed",
+	"APPROVAL_NOT_FOUND":                  "user has not approved the current version of this bid",
+	"TEMPLATE_NOT_FOUND":                  "template does not exist",
+	"SERVICE_TYPE_UNKNOWN":                "service type does not exist",
+	"SERVICE_TYPE_EXISTS":                 "service type already exists",
+	"SERVICE_TYPE_IN_USE":                 "service type is used by tenders, templates or subcategories and can only be deactivated",
+	"SERVICE_TYPE_PARENT_INVALID":         "parent must be an existing top-level category",
+	"NOT_INVITED":                         "bidder has no accepted invitation to this tender",
+	"NOT_INVITEE":                         "invitation is addressed to someone else",
+	"INVITATION_NOT_FOUND":                "invitation does not exist",
+	"INVITATION_EXISTS":                   "invitation already exists",
+	"INVITEE_NOT_FOUND":                   "invited user or organization does not exist",
+	"SUPPLIER_BLOCKED":                    "bidder is on the blocklist of the tender organization",
+	"SUPPLIER_NOT_QUALIFIED":              "tender requires a qualified supplier",
+	"SUPPLIER_QUALIFICATION_EXPIRED":      "supplier qualification has expired",
+	"SUPPLIER_NOT_FOUND":                  "supplier user or organization does not exist",
+	"SUPPLIER_ENTRY_NOT_FOUND":            "supplier list entry does not exist",
+	"SUPPLIER_ENTRY_EXISTS":               "supplier is already on this list",
+	"QUALIFICATION_REQUIREMENT_NOT_FOUND": "qualification is not required for this service type",
+	"BULK_EMPTY":                          "bulk request contains no items",
+	"BULK_TOO_LARGE":                      "bulk request contains too many items",
+	"BULK_ROLLED_BACK":                    "item succeeded but the transaction was rolled back",
+	"BULK_NOT_PROCESSED":                  "item was not processed because an earlier item failed",
+	"IMPORT_EXISTS":                       "external id is being imported by another request",
+	"IDEMPOTENCY_KEY_INVALID":             "Idempotency-Key must be 1 to 255 visible ASCII characters",
+	"IDEMPOTENCY_KEY_REUSED":              "Idempotency-Key was already used with a different request",
+	"IDEMPOTENCY_KEY_IN_PROGRESS":         "request with this Idempotency-Key is still being processed",
+
+	"validation.required":        "must be provided",
+	"validation.maxRunes":        "must not be longer than %d symbols",
+	"validation.oneOf":           "must be one of: %s",
+	"validation.uuid":            "must be a valid UUID",
+	"validation.rfc3339":         "must be a date-time in RFC3339 format",
+	"validation.singleValue":     "must contain only one value",
+	"validation.integer":         "must be an integer value",
+	"validation.min":             "must be greater than or equal to %d",
+	"validation.max":             "must not be greater than %d",
+	"validation.version":         "must be an integer greater than 0",
+	"validation.unknownService":  "service %s does not exist",
+	"validation.inactiveService": "service type %s is not active",
+	"validation.unknownStatus":   "status %s does not exist",
+	"validation.duplicate":       "%s is already used by item %d",
+
+	"status.tender.Created":   "Created",
+	"status.tender.Published": "Published",
+	"status.tender.Closed":    "Closed",
+	"status.bid.Created":      "Created",
+	"status.bid.Published":    "Published",
+	"status.bid.Withdrawn":    "Withdrawn",
+	"status.bid.Canceled":     "Canceled",
+}
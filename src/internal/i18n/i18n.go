@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const Default = "en"
+
+// Bundle maps message keys to fmt templates.
+type Bundle map[string]string
+
+var bundles = map[string]Bundle{
+	"en": english,
+	"ru": russian,
+}
+
+func Languages() []string {
+	res := make([]string, 0, len(bundles))
+	for lang := range bundles {
+		res = append(res, lang)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Negotiate picks the supported language with the highest weight in an
+// Accept-Language header, falling back to English.
+func Negotiate(header string) string {
+	best, bestWeight := Default, 0.0
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := bundles[primary]; !ok {
+			continue
+		}
+
+		weight := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		if weight > bestWeight {
+			best, bestWeight = primary, weight
+		}
+	}
+
+	return best
+}
+
+// Lookup returns the message for key in lang without falling back.
+func Lookup(lang, key string, args ...any) (string, bool) {
+	template, ok := bundles[lang][key]
+	if !ok {
+		return "", false
+	}
+	if len(args) == 0 {
+		return template, true
+	}
+	return fmt.Sprintf(template, args...), true
+}
+
+// T returns the message for key in lang, falling back to English and then
+// to the key itself.
+func T(lang, key string, args ...any) string {
+	if message, ok := Lookup(lang, key, args...); ok {
+		return message
+	}
+	if message, ok := Lookup(Default, key, args...); ok {
+		return message
+	}
+	return key
+}
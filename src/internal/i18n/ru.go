@@ -0,0 +1,78 @@
+package i18n
+
+var russian = Bundle{
+	"VALIDATION_FAILED":                   "параметры запроса не прошли проверку",
+	"UNAUTHORIZED":                        "пользователь не существует или указан неверно",
+	"FORBIDDEN":                           "недостаточно прав для выполнения действия",
+	"RATE_LIMITED":                        "превышен лимит запросов",
+	"INTERNAL_ERROR":                      "на сервере произошла ошибка, запрос не обработан",
+	"USER_NOT_FOUND":                      "пользователь не существует или указан неверно",
+	"ORGANIZATION_NOT_FOUND":              "пользователь не является ответственным ни за одну организацию",
+	"NO_RIGHTS":                           "недостаточно прав для выполнения действия",
+	"TENDER_NOT_FOUND":                    "тендер не найден",
+	"TENDER_VERSION_NOT_FOUND":            "тендер или его версия не найдены",
+	"TENDER_NOT_PUBLISHED":                "нельзя сделать предложение по неопубликованному тендеру",
+	"TENDER_INACTIVE":                     "нельзя принять решение по неактивному тендеру",
+	"NOT_TENDER_RESPONSIBLE":              "пользователь не является ответственным за этот тендер",
+	"BID_NOT_FOUND":                       "предложение не найдено",
+	"BID_OR_TENDER_NOT_FOUND":             "предложение или тендер не найдены",
+	"BID_VERSION_NOT_FOUND":               "версия предложения не найдена",
+	"BID_INACTIVE":                        "нельзя принять решение по неактивному предложению",
+	"BID_ALREADY_APPROVED":                "пользователь уже согласовал это предложение",
+	"SELF_BID_FORBIDDEN":                  "нельзя делать предложения по собственному тендеру",
+	"NOT_BID_RESPONSIBLE":                 "пользователь не является ответственным за это предложение",
+	"BID_NOT_WITHDRAWABLE":                "отозвать можно только созданное или опубликованное предложение",
+	"BID_NOT_WITHDRAWN":                   "повторно подать можно только отозванное предложение",
+	"BID_WITHDRAWN":                       "предложение отозвано, его можно только подать повторно",
+	"BID_NOT_EDITABLE":                    "редактировать можно только созданное или опубликованное предложение",
+	"TENDER_DEADLINE_PASSED":              "срок подачи предложений по тендеру истек",
+	"APPROVAL_NOT_FOUND":                  "пользователь не согласовывал текущую версию предложения",
+	"TEMPLATE_NOT_FOUND":                  "шаблон не найден",
+	"SERVICE_TYPE_UNKNOWN":                "такого типа услуг не существует",
+	"SERVICE_TYPE_EXISTS":                 "такой тип услуг уже существует",
+	"SERVICE_TYPE_IN_USE":                 "тип услуг используется в тендерах, шаблонах или подкатегориях, его можно только деактивировать",
+	"SERVICE_TYPE_PARENT_INVALID":         "родителем может быть только существующая категория верхнего уровня",
+	"NOT_INVITED":                         "у участника нет принятого приглашения в этот тендер",
+	"NOT_INVITEE":                         "приглашение адресовано другому участнику",
+	"INVITATION_NOT_FOUND":                "приглашение не найдено",
+	"INVITATION_EXISTS":                   "приглашение уже существует",
+	"INVITEE_NOT_FOUND":                   "приглашенный пользователь или организация не существует",
+	"SUPPLIER_BLOCKED":                    "участник находится в черном списке организации тендера",
+	"SUPPLIER_NOT_QUALIFIED":              "тендер требует квалифицированного поставщика",
+	"SUPPLIER_QUALIFICATION_EXPIRED":      "срок квалификации поставщика истек",
+	"SUPPLIER_NOT_FOUND":                  "пользователь или организация поставщика не существует",
+	"SUPPLIER_ENTRY_NOT_FOUND":            "запись в списке поставщиков не найдена",
+	"SUPPLIER_ENTRY_EXISTS":               "поставщик уже есть в этом списке",
+	"QUALIFICATION_REQUIREMENT_NOT_FOUND": "для этого типа услуг квалификация не требуется",
+	"BULK_EMPTY":                          "в пакетном запросе нет элементов",
+	"BULK_TOO_LARGE":                      "в пакетном запросе слишком много элементов",
+	"BULK_ROLLED_BACK":                    "элемент обработан, но транзакция отменена",
+	"BULK_NOT_PROCESSED":                  "элемент не обработан из-за ошибки в предыдущем элементе",
+	"IMPORT_EXISTS":                       "внешний идентификатор импортируется другим запросом",
+	"IDEMPOTENCY_KEY_INVALID":             "Idempotency-Key должен состоять из 1–255 видимых символов ASCII",
+	"IDEMPOTENCY_KEY_REUSED":              "Idempotency-Key уже использован с другим запросом",
+	"IDEMPOTENCY_KEY_IN_PROGRESS":         "запрос с этим Idempotency-Key еще выполняется",
+
+	"validation.required":        "обязательное поле",
+	"validation.maxRunes":        "не должно быть длиннее %d символов",
+	"validation.oneOf":           "допустимые значения: %s",
+	"validation.uuid":            "должно быть корректным UUID",
+	"validation.rfc3339":         "должно быть датой и временем в формате RFC3339",
+	"validation.singleValue":     "должно содержать только одно значение",
+	"validation.integer":         "должно быть целым числом",
+	"validation.min":             "должно быть не меньше %d",
+	"validation.max":             "должно быть не больше %d",
+	"validation.version":         "должно быть целым числом больше 0",
+	"validation.unknownService":  "типа услуг %s не существует",
+	"validation.inactiveService": "тип услуг %s неактивен",
+	"validation.unknownStatus":   "статуса %s не существует",
+	"validation.duplicate":       "%s уже указан в элементе %d",
+
+	"status.tender.Created":   "Создан",
+	"status.tender.Published": "Опубликован",
+	"status.tender.Closed":    "Закрыт",
+	"status.bid.Created":      "Создано",
+	"status.bid.Published":    "Опубликовано",
+	"status.bid.Withdrawn":    "Отозвано",
+	"status.bid.Canceled":     "Отменено",
+}
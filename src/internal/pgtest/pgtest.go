@@ -0,0 +1,175 @@
+// Package pgtest runs integration tests against a throwaway PostgreSQL
+// cluster. The cluster is created with the initdb and pg_ctl binaries
+// installed on the machine, listens only on a socket in a temporary
+// directory and is removed when the tests finish. Tests are skipped when
+// the binaries cannot be found.
+package pgtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// port only names the socket file; nothing listens on TCP.
+const port = "5432"
+
+type cluster struct {
+	dir   string
+	pgCtl string
+	admin *sql.DB
+}
+
+var (
+	once    sync.Once
+	current *cluster
+	failure error
+
+	mu        sync.Mutex
+	databases int
+)
+
+// Main runs the tests of a package and stops the cluster afterwards, if one
+// was started. Packages that call Open must call it from TestMain.
+func Main(m *testing.M) {
+	code := m.Run()
+	if current != nil {
+		current.stop()
+	}
+	os.Exit(code)
+}
+
+// Open returns a connection to a new, empty database that is dropped when
+// the test ends. The cluster is started on first use.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	once.Do(func() {
+		current, failure = start()
+	})
+	if failure != nil {
+		t.Skipf("PostgreSQL is not available: %v", failure)
+	}
+
+	mu.Lock()
+	databases++
+	name := fmt.Sprintf("test_%d", databases)
+	mu.Unlock()
+
+	if _, err := current.admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("postgres", current.dsn(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := current.admin.Exec("DROP DATABASE " + name); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+	})
+	return db
+}
+
+// binary finds a PostgreSQL server binary on PATH or in the usual
+// installation directories, preferring the newest version.
+func binary(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	var found []string
+	for _, pattern := range []string{
+		"/usr/lib/postgresql/*/bin/",
+		"/usr/pgsql-*/bin/",
+		"/usr/local/opt/postgresql*/bin/",
+		"/opt/homebrew/opt/postgresql*/bin/",
+	} {
+		matches, _ := filepath.Glob(pattern + name)
+		found = append(found, matches...)
+	}
+	if len(found) == 0 {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	slices.Sort(found)
+	return found[len(found)-1], nil
+}
+
+func start() (*cluster, error) {
+	initdb, err := binary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	pgCtl, err := binary("pg_ctl")
+	if err != nil {
+		return nil, err
+	}
+	if os.Geteuid() == 0 {
+		return nil, errors.New("initdb cannot be run as root")
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest")
+	if err != nil {
+		return nil, err
+	}
+	c := &cluster{dir: dir, pgCtl: pgCtl}
+
+	out, err := exec.Command(initdb, "-D", c.data(), "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %w\n%s", err, out)
+	}
+
+	options := fmt.Sprintf("-k %s -p %s -c listen_addresses='' -F", dir, port)
+	out, err = exec.Command(pgCtl, "start", "-w", "-D", c.data(), "-l", filepath.Join(dir, "server.log"), "-o", options).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("pg_ctl start: %w\n%s", err, out)
+	}
+
+	c.admin, err = sql.Open("postgres", c.dsn("postgres"))
+	if err == nil {
+		err = c.ping()
+	}
+	if err != nil {
+		c.stop()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *cluster) data() string {
+	return filepath.Join(c.dir, "data")
+}
+
+func (c *cluster) dsn(database string) string {
+	return fmt.Sprintf("host=%s port=%s user=postgres dbname=%s sslmode=disable", c.dir, port, database)
+}
+
+func (c *cluster) ping() error {
+	var err error
+	for range 50 {
+		if err = c.admin.Ping(); err == nil {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return err
+}
+
+func (c *cluster) stop() {
+	if c.admin != nil {
+		c.admin.Close()
+	}
+	exec.Command(c.pgCtl, "stop", "-D", c.data(), "-m", "immediate").Run()
+	os.RemoveAll(c.dir)
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// Store keeps token buckets. The in-process MemoryStore is the default;
+// other implementations can share buckets between replicas.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	key      string
+	tokens   float64
+	lastSeen time.Time
+}
+
+// MemoryStore keeps at most maxBuckets buckets. When it is full, the bucket
+// used least recently is evicted, which resets that client's limit.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxBuckets int
+	buckets    map[string]*list.Element
+	// recent orders the buckets from most to least recently used.
+	recent *list.List
+}
+
+func NewMemoryStore(maxBuckets int) *MemoryStore {
+	return &MemoryStore{maxBuckets: maxBuckets, buckets: map[string]*list.Element{}, recent: list.New()}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b *bucket
+	if e, ok := s.buckets[key]; ok {
+		b = e.Value.(*bucket)
+		s.recent.MoveToFront(e)
+	} else {
+		if len(s.buckets) >= s.maxBuckets {
+			oldest := s.recent.Back()
+			s.recent.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*bucket).key)
+		}
+		b = &bucket{key: key, tokens: float64(limit.Burst), lastSeen: now}
+		s.buckets[key] = s.recent.PushFront(b)
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.RPS)
+	}
+	b.lastSeen = now
+
+	res := Result{Limit: limit.Burst}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else if limit.RPS > 0 {
+		res.RetryAfter = time.Duration((1 - b.tokens) / limit.RPS * float64(time.Second))
+	} else {
+		res.RetryAfter = time.Hour
+	}
+
+	res.Remaining = int(math.Floor(b.tokens))
+	if limit.RPS > 0 {
+		res.ResetAfter = time.Duration((float64(limit.Burst) - b.tokens) / limit.RPS * float64(time.Second))
+	}
+
+	return res, nil
+}
+
+// Cleanup drops buckets that have not been used since idleSince.
+func (s *MemoryStore) Cleanup(idleSince time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for e := s.recent.Back(); e != nil; e = s.recent.Back() {
+		b := e.Value.(*bucket)
+		if !b.lastSeen.Before(idleSince) {
+			break
+		}
+		s.recent.Remove(e)
+		delete(s.buckets, b.key)
+		removed++
+	}
+	return removed
+}
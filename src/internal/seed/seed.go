@@ -0,0 +1,310 @@
+// Package seed fills a database with demo organizations, employees, tenders
+// and bids for local development and tests. The same seed value always
+// produces the same ids, names, statuses, versions and approvals.
+package seed
+
+import (
+	"avitotask/internal/data"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrAlreadySeeded = errors.New("database already holds the data of this seed")
+
+// namespace derives the ids of seeded rows from the seed value.
+var namespace = uuid.MustParse("6f1c2b0e-5d8a-4c4e-9a57-3b1f0d2e8c61")
+
+// baseTime is when the first seeded tender was created; later ones follow.
+var baseTime = time.Date(2024, time.January, 15, 9, 0, 0, 0, time.UTC)
+
+type Options struct {
+	Seed int64
+	// Organizations is the number of organizations, each with Employees
+	// responsible employees and Tenders tenders.
+	Organizations int
+	Employees     int
+	Tenders       int
+	// Bids is the number of bids on each tender that accepts them.
+	Bids int
+	// Quorum is the approval quorum the service runs with. Closed tenders
+	// get a bid approved by the quorum; open ones stay below it.
+	Quorum int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Seed:          1,
+		Organizations: 3,
+		Employees:     3,
+		Tenders:       6,
+		Bids:          4,
+		Quorum:        3,
+	}
+}
+
+func (o Options) validate() error {
+	switch {
+	case o.Organizations < 2:
+		return errors.New("at least two organizations are needed, so that bids come from another one")
+	case o.Employees < 1:
+		return errors.New("every organization needs at least one employee")
+	case o.Tenders < 0 || o.Bids < 0:
+		return errors.New("numbers of tenders and bids must not be negative")
+	case o.Quorum < 1:
+		return errors.New("quorum must be at least 1")
+	}
+	return nil
+}
+
+// Result holds everything that was created, in creation order.
+type Result struct {
+	Organizations []*data.Organization `json:"organizations"`
+	Employees     []*data.Employee     `json:"employees"`
+	// Members maps organization ids to the ids of their employees.
+	Members map[string][]string `json:"members"`
+	Tenders []*data.Tender      `json:"tenders"`
+	Bids    []*data.Bid         `json:"bids"`
+}
+
+// Prepare creates the platform tables and applies the migrations, so that
+// Run can be used on an empty database.
+func Prepare(models data.Models) error {
+	if err := models.Tables.CreatePlatformTables(); err != nil {
+		return err
+	}
+	return models.Tables.CreateTables()
+}
+
+type seeder struct {
+	tx   *data.Tx
+	opts Options
+	rng  *rand.Rand
+	res  *Result
+	// clock is the creation time of the next tender or bid.
+	clock time.Time
+}
+
+// serviceTypes are the codes the migrations put into the service type
+// catalogue. They are used instead of the live catalogue so that the data
+// does not depend on what administrators changed there.
+var serviceTypes = []string{"Construction", "Delivery", "Manufacture"}
+
+// Run creates the demo data of opts.Seed in one transaction, so that a
+// failed run leaves nothing behind. The schema must be in place, see
+// Prepare. Running a seed a second time returns ErrAlreadySeeded.
+func Run(models data.Models, opts Options) (*Result, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := models.Begin()
+	if err != nil {
+		return nil, err
+	}
+	s := &seeder{
+		tx:    tx,
+		opts:  opts,
+		rng:   rand.New(rand.NewSource(opts.Seed)),
+		res:   &Result{Members: make(map[string][]string)},
+		clock: baseTime,
+	}
+	if err := s.run(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.res, nil
+}
+
+func (s *seeder) run() error {
+	exists, err := s.tx.OrganizationExists(s.id("organization", 0))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadySeeded
+	}
+
+	for i := range s.opts.Organizations {
+		if err := s.organization(i); err != nil {
+			return err
+		}
+	}
+	for i, organization := range s.res.Organizations {
+		for j := range s.opts.Tenders {
+			if err := s.tender(organization, i*s.opts.Tenders+j, s.pick(serviceTypes)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// id returns the id of the n-th row of a kind for the seed.
+func (s *seeder) id(kind string, n int) string {
+	return uuid.NewSHA1(namespace, []byte(fmt.Sprintf("%d/%s/%d", s.opts.Seed, kind, n))).String()
+}
+
+func (s *seeder) pick(values []string) string {
+	return values[s.rng.Intn(len(values))]
+}
+
+func (s *seeder) tick() string {
+	s.clock = s.clock.Add(time.Duration(1+s.rng.Intn(48)) * time.Hour)
+	return s.clock.Format(time.RFC3339)
+}
+
+func (s *seeder) organization(i int) error {
+	organization := &data.Organization{
+		Id:          s.id("organization", i),
+		Name:        fmt.Sprintf("%s %s", s.pick(companyWords), s.pick(companySuffixes)),
+		Description: "Demo organization",
+		Type:        data.OrganizationTypes[i%len(data.OrganizationTypes)],
+	}
+	if err := s.tx.InsertOrganization(organization); err != nil {
+		return err
+	}
+	s.res.Organizations = append(s.res.Organizations, organization)
+
+	for j := range s.opts.Employees {
+		n := i*s.opts.Employees + j
+		first, last := s.pick(firstNames), s.pick(lastNames)
+		// Usernames are unique in the platform, so they carry the seed like
+		// the ids do.
+		employee := &data.Employee{
+			Id:        s.id("employee", n),
+			Username:  fmt.Sprintf("%s.%s.%d.%d", strings.ToLower(first), strings.ToLower(last), s.opts.Seed, n+1),
+			FirstName: first,
+			LastName:  last,
+		}
+		if err := s.tx.InsertEmployee(employee); err != nil {
+			return err
+		}
+		if err := s.tx.AddResponsible(organization.Id, employee.Id); err != nil {
+			return err
+		}
+		s.res.Employees = append(s.res.Employees, employee)
+		s.res.Members[organization.Id] = append(s.res.Members[organization.Id], employee.Id)
+	}
+	return nil
+}
+
+// tender creates the n-th tender. Statuses cycle through Created, Published
+// and Closed; the last two get bids, and a closed tender has a bid approved
+// by the quorum.
+func (s *seeder) tender(organization *data.Organization, n int, serviceType string) error {
+	status := data.TenderStatuses[n%len(data.TenderStatuses)]
+	subject := s.pick(tenderSubjects)
+	tender := &data.Tender{
+		Id:             s.id("tender", n),
+		Name:           fmt.Sprintf("%s #%d", subject, n+1),
+		Description:    fmt.Sprintf("%s for %s", subject, organization.Name),
+		ServiceType:    serviceType,
+		Status:         status,
+		OrganizationId: organization.Id,
+		Visibility:     data.VisibilityPublic,
+		Version:        1,
+		CreatedAt:      s.tick(),
+	}
+	if err := s.tx.InsertTender(tender); err != nil {
+		return err
+	}
+
+	for range s.rng.Intn(3) {
+		edit := data.Tender{Description: fmt.Sprintf("%s, revised", tender.Description)}
+		updated, err := s.tx.UpdateTender(tender.Id, edit)
+		if err != nil {
+			return err
+		}
+		tender = updated
+	}
+	s.res.Tenders = append(s.res.Tenders, tender)
+
+	if status == "Created" {
+		return nil
+	}
+	for j := range s.opts.Bids {
+		if err := s.bid(tender, n*s.opts.Bids+j, j, status == "Closed" && j == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bid creates the n-th bid, the i-th on its tender, by an employee or as an
+// organization other than the tender's. Published bids get approvals from
+// the tender organization: the winner as many as the quorum, the others
+// fewer.
+func (s *seeder) bid(tender *data.Tender, n, i int, winner bool) error {
+	var others []*data.Organization
+	for _, organization := range s.res.Organizations {
+		if organization.Id != tender.OrganizationId {
+			others = append(others, organization)
+		}
+	}
+	author := others[s.rng.Intn(len(others))]
+
+	bid := &data.Bid{
+		Id:          s.id("bid", n),
+		Name:        fmt.Sprintf("Offer from %s", author.Name),
+		Description: fmt.Sprintf("%s: %s", author.Name, s.pick(bidPitches)),
+		Status:      data.BidStatuses[i%len(data.BidStatuses)],
+		TenderId:    tender.Id,
+		AuthorType:  "Organization",
+		AuthorId:    author.Id,
+		Version:     1,
+		CreatedAt:   s.tick(),
+	}
+	if winner {
+		bid.Status = "Published"
+	}
+	if s.rng.Intn(2) == 0 {
+		members := s.res.Members[author.Id]
+		bid.AuthorType = "User"
+		bid.AuthorId = members[s.rng.Intn(len(members))]
+	}
+	if err := s.tx.InsertBid(bid); err != nil {
+		return err
+	}
+
+	for range s.rng.Intn(3) {
+		edit := data.Bid{Description: fmt.Sprintf("%s, %s", bid.Description, s.pick(bidPitches))}
+		updated, err := s.tx.EditBid(bid.Id, edit)
+		if err != nil {
+			return err
+		}
+		bid = updated
+	}
+
+	if bid.Status == "Published" {
+		reviewers := s.res.Members[tender.OrganizationId]
+		needed := min(s.opts.Quorum, len(reviewers))
+		approvals := needed
+		if !winner {
+			approvals = s.rng.Intn(needed)
+		}
+		for _, userId := range reviewers[:approvals] {
+			if err := s.tx.ApproveDecision(bid.Id, userId); err != nil {
+				return err
+			}
+		}
+	}
+	s.res.Bids = append(s.res.Bids, bid)
+	return nil
+}
+
+var (
+	firstNames      = []string{"Anna", "Boris", "Daria", "Egor", "Irina", "Kirill", "Maria", "Nikita", "Olga", "Pavel", "Sofia", "Timur"}
+	lastNames       = []string{"Ivanov", "Smirnov", "Kuznetsov", "Popov", "Sokolov", "Lebedev", "Kozlov", "Novikov", "Morozov", "Volkov"}
+	companyWords    = []string{"Northern", "Granite", "Volga", "Baltic", "Summit", "Ural", "Cedar", "Harbor", "Meridian", "Polar"}
+	companySuffixes = []string{"Logistics", "Builders", "Works", "Supply", "Industries", "Partners"}
+	tenderSubjects  = []string{"Office renovation", "Warehouse delivery", "Furniture manufacture", "Road repair", "Packaging supply", "Parcel delivery", "Steel frame assembly"}
+	bidPitches      = []string{"fixed price", "two-week delivery", "five-year warranty", "own equipment", "certified staff", "flexible schedule"}
+)
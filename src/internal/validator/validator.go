@@ -1,11 +1,30 @@
 package validator
 
+import (
+	"avitotask/internal/i18n"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
 type Validator struct {
-	Errors map[string]string
+	Errors   map[string]string
+	Messages map[string]Message
+}
+
+// Message is a catalogue key with its arguments, kept alongside the English
+// text so responses can be rendered in the client's language.
+type Message struct {
+	Key  string
+	Args []any
 }
 
 func New() *Validator {
-	return &Validator{Errors: make(map[string]string)}
+	return &Validator{Errors: make(map[string]string), Messages: make(map[string]Message)}
 }
 
 func (v *Validator) Valid() bool {
@@ -24,6 +43,159 @@ func (v *Validator) CheckError(ok bool, key, value string) {
 	}
 }
 
+// AddMessage records a catalogue message under key.
+func (v *Validator) AddMessage(key, messageKey string, args ...any) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = i18n.T(i18n.Default, messageKey, args...)
+		v.Messages[key] = Message{Key: messageKey, Args: args}
+	}
+}
+
+func (v *Validator) CheckMessage(ok bool, key, messageKey string, args ...any) {
+	if !ok {
+		v.AddMessage(key, messageKey, args...)
+	}
+}
+
+// Localized returns the errors translated to lang. Errors added as plain
+// text are returned unchanged.
+func (v *Validator) Localized(lang string) map[string]string {
+	res := make(map[string]string, len(v.Errors))
+	for key, value := range v.Errors {
+		if message, ok := v.Messages[key]; ok {
+			value = i18n.T(lang, message.Key, message.Args...)
+		}
+		res[key] = value
+	}
+	return res
+}
+
 func (v *Validator) StringNotEmpty(s string) bool {
 	return len(s) > 0
 }
+
+// Rule is a single declarative check on a string field. Every rule except
+// Required passes on an empty value, so optional fields only need to be
+// checked when they are present.
+type Rule struct {
+	Kind    string
+	Max     int
+	Values  []string
+	Message Message
+	check   func(string) bool
+}
+
+const (
+	KindRequired = "required"
+	KindMaxRunes = "maxRunes"
+	KindOneOf    = "oneOf"
+	KindUUID     = "uuid"
+	KindRFC3339  = "rfc3339"
+)
+
+func Required() Rule {
+	return Rule{
+		Kind:    KindRequired,
+		Message: Message{Key: "validation.required"},
+		check:   func(s string) bool { return s != "" },
+	}
+}
+
+func MaxRunes(n int) Rule {
+	return Rule{
+		Kind:    KindMaxRunes,
+		Max:     n,
+		Message: Message{Key: "validation.maxRunes", Args: []any{n}},
+		check:   func(s string) bool { return utf8.RuneCountInString(s) <= n },
+	}
+}
+
+func OneOf(values ...string) Rule {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	return Rule{
+		Kind:    KindOneOf,
+		Values:  sorted,
+		Message: Message{Key: "validation.oneOf", Args: []any{strings.Join(sorted, ", ")}},
+		check:   func(s string) bool { return slices.Contains(sorted, s) },
+	}
+}
+
+func UUID() Rule {
+	return Rule{
+		Kind:    KindUUID,
+		Message: Message{Key: "validation.uuid"},
+		check: func(s string) bool {
+			_, err := uuid.Parse(s)
+			return err == nil
+		},
+	}
+}
+
+func RFC3339() Rule {
+	return Rule{
+		Kind:    KindRFC3339,
+		Message: Message{Key: "validation.rfc3339"},
+		check: func(s string) bool {
+			_, err := time.Parse(time.RFC3339, s)
+			return err == nil
+		},
+	}
+}
+
+// FieldRules maps field names, as they appear in JSON, to their rules.
+type FieldRules map[string][]Rule
+
+// Optional returns a copy of the rules without Required checks, for partial
+// updates where every field may be left out.
+func (rules FieldRules) Optional() FieldRules {
+	res := make(FieldRules, len(rules))
+	for name, fieldRules := range rules {
+		for _, rule := range fieldRules {
+			if rule.Kind != KindRequired {
+				res[name] = append(res[name], rule)
+			}
+		}
+	}
+	return res
+}
+
+// Field checks value against rules, recording the first failure under key.
+func (v *Validator) Field(key, value string, rules ...Rule) {
+	for _, rule := range rules {
+		if rule.Kind != KindRequired && value == "" {
+			continue
+		}
+		if !rule.check(value) {
+			v.AddMessage(key, rule.Message.Key, rule.Message.Args...)
+			return
+		}
+	}
+}
+
+// Struct applies rules to the string fields of s, matched by json tag.
+// Fields of embedded structs are checked as if they were fields of s.
+func (v *Validator) Struct(s any, rules FieldRules) {
+	v.structValue(reflect.Indirect(reflect.ValueOf(s)), rules)
+}
+
+func (v *Validator) structValue(val reflect.Value, rules FieldRules) {
+	t := val.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			v.structValue(val.Field(i), rules)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+		fieldRules, ok := rules[name]
+		if !ok || f.Type.Kind() != reflect.String {
+			continue
+		}
+		v.Field(name, val.Field(i).String(), fieldRules...)
+	}
+}